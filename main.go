@@ -24,7 +24,6 @@ import (
 	"os/exec"
 	"os/signal"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/yaacov/observer/observer"
@@ -63,6 +62,9 @@ func printUsage() {
 	fmt.Println("Examples:")
 	fmt.Println("  observer -w main.c -r ./run.sh")
 	fmt.Println("  observer -w main.c -w src/*.c -r run.sh -d 1")
+	fmt.Println("  observer -w 'src/**/*.go' -x vendor/ --gitignore -r 'go test ./...'")
+	fmt.Println("  observer -w 'certs/*.pem' --ops create,write,rename,chmod -r ./reload.sh")
+	fmt.Println("  observer -w 'src/*.go' --hash 1048576 -r 'go build'")
 
 	os.Exit(1)
 }
@@ -70,15 +72,18 @@ func printUsage() {
 func main() {
 	var err error
 	var watchFiles arrayFlags
+	var excludeFiles arrayFlags
 	var scripts arrayFlags
 
-	// Create a mutex for the event listener.
-	mutex := &sync.Mutex{}
-
 	// Parse cli arguments.
 	flag.Var(&watchFiles, "w", "list of files to watch.")
+	flag.Var(&excludeFiles, "x", "list of files to exclude (.gitignore syntax).")
+	flag.Var(&excludeFiles, "exclude", "list of files to exclude (.gitignore syntax).")
 	flag.Var(&scripts, "r", "list of scripts to run on file modifiaction event.")
+	gitignorePtr := flag.Bool("gitignore", false, "exclude files listed in .gitignore and .ignore files.")
+	opsPtr := flag.String("ops", "create,write,remove", "file operations to watch, create, write, remove, rename, chmod, modified or all.")
 	bufferSecPtr := flag.Int("d", 0, "buffer events for N sec.")
+	hashPtr := flag.Int64("hash", 0, "run scripts only when file content changed, hash files up to N bytes.")
 	verbosePtr := flag.Bool("V", false, "dump debug data.")
 
 	flag.Usage = printUsage
@@ -96,6 +101,13 @@ func main() {
 		flag.Usage()
 	}
 
+	// Check file operations.
+	ops, err := observer.ParseOp(*opsPtr)
+	if err != nil {
+		fmt.Println("[Error]", err)
+		flag.Usage()
+	}
+
 	// Open observer and start watching.
 	o := observer.Observer{}
 	defer o.Close()
@@ -103,30 +115,41 @@ func main() {
 	// Set verbosity.
 	o.Verbose = *verbosePtr
 
+	// Run scripts one event at a time, in event order.
+	o.SetDeliveryMode(observer.Sequential)
+
 	// Set damping time.
 	if *bufferSecPtr != 0 {
 		sec := time.Duration(*bufferSecPtr) * time.Second
 		o.SetBufferDuration(sec)
 	}
 
+	// Set content hash.
+	if *hashPtr != 0 {
+		o.SetContentHash(*hashPtr)
+	}
+
+	// Set excluded files.
+	o.SetGitignore(*gitignorePtr)
+	err = o.Ignore(excludeFiles)
+	if err != nil {
+		log.Fatal("[Error] exclude files: ", err)
+	}
+
 	// Watch for changes in files.
-	err = o.Watch(watchFiles)
+	err = o.WatchOps(watchFiles, ops)
 	if err != nil {
 		log.Fatal("[Error] watch files: ", err)
 	}
 
 	// Add a listener for events.
 	o.AddListener(func(e interface{}) {
-		// Lock the listener.
-		mutex.Lock()
-		defer mutex.Unlock()
-
 		// Log the event.
 		log.Printf("[Info]Received: %v\n", e)
 
 		for _, s := range scripts {
 			// Try to run a script. and check for errors running script.
-			if err = runScript(s); err != nil {
+			if err := runScript(s); err != nil {
 				log.Printf("[Error] running event listener: %s\n", err)
 			}
 		}
@@ -0,0 +1,155 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Backend is a file watcher, a backend watch directories and send events
+// for files created, modified or removed in the watched directories.
+//
+// Event names are the watched directory name joined with the file name,
+// when a watched directory is removed, a Remove event is sent with the
+// directory name, and the directory is no longer watched.
+// The Events and Errors channels are closed when the backend is closed.
+//
+// Backends may also implement a Handled() method, called by the observer
+// after each event or error is handled, e.g. test backends waiting for
+// events to be handled.
+type Backend interface {
+	// Add starts watching a directory.
+	Add(dir string) error
+
+	// Remove stops watching a directory.
+	Remove(dir string) error
+
+	// Events returns the file events channel.
+	Events() <-chan WatchEvent
+
+	// Errors returns the errors channel.
+	Errors() <-chan error
+
+	// Close removes all watches and closes the events and errors channels.
+	Close() error
+}
+
+// fsnotifyBackend is a Backend using fsnotify.
+type fsnotifyBackend struct {
+	watcher *fsnotify.Watcher
+	events  chan WatchEvent
+	errors  chan error
+	done    chan struct{}
+	once    sync.Once
+	wg      sync.WaitGroup
+}
+
+// NewFSNotifyBackend returns a Backend using fsnotify, this is the default
+// backend used by the observer.
+func NewFSNotifyBackend() (Backend, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &fsnotifyBackend{
+		watcher: watcher,
+		events:  make(chan WatchEvent),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.loop()
+
+	return b, nil
+}
+
+// Add starts watching a directory.
+func (b *fsnotifyBackend) Add(dir string) error {
+	return b.watcher.Add(dir)
+}
+
+// Remove stops watching a directory.
+func (b *fsnotifyBackend) Remove(dir string) error {
+	return b.watcher.Remove(dir)
+}
+
+// Events returns the file events channel.
+func (b *fsnotifyBackend) Events() <-chan WatchEvent {
+	return b.events
+}
+
+// Errors returns the errors channel.
+func (b *fsnotifyBackend) Errors() <-chan error {
+	return b.errors
+}
+
+// Close removes all watches and closes the events and errors channels.
+func (b *fsnotifyBackend) Close() (err error) {
+	b.once.Do(func() {
+		close(b.done)
+		err = b.watcher.Close()
+	})
+
+	// Wait for the events loop to close the channels.
+	b.wg.Wait()
+
+	return
+}
+
+// loop converts fsnotify events into observer events.
+func (b *fsnotifyBackend) loop() {
+	defer b.wg.Done()
+	defer close(b.events)
+	defer close(b.errors)
+
+	for {
+		select {
+		case event, ok := <-b.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Convert fsnotify Event into observer Event
+			e := WatchEvent{
+				Name: event.Name,
+				Op:   Op(event.Op) & (Create | Write | Remove | Rename | Chmod),
+			}
+
+			select {
+			case b.events <- e:
+			case <-b.done:
+				return
+			}
+		case err, ok := <-b.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			select {
+			case b.errors <- err:
+			case <-b.done:
+				return
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
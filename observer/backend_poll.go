@@ -0,0 +1,328 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileState is the file state compared by the polling backend.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    os.FileMode
+	inode   uint64
+}
+
+// PollingBackend is a Backend comparing the size, modification time, inode
+// and mode of files in the watched directories, it can be used on file
+// systems not supported by fsnotify, for example NFS, FUSE and some
+// container bind mounts and overlay file systems.
+type PollingBackend struct {
+	interval time.Duration
+	dirs     map[string]map[string]fileState
+	events   chan WatchEvent
+	errors   chan error
+	done     chan struct{}
+	mutex    sync.Mutex
+	poll     sync.Mutex
+	cookie   uint32
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewPollingBackend returns a Backend polling the watched directories every
+// interval, if interval is zero directories are polled only when calling
+// Poll.
+//
+// Files renamed inside a watched directory are sent as a Rename event with
+// the old name and a Create event with the new name, both events have the
+// same Cookie.
+func NewPollingBackend(interval time.Duration) *PollingBackend {
+	b := &PollingBackend{
+		interval: interval,
+		dirs:     make(map[string]map[string]fileState),
+		events:   make(chan WatchEvent),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+
+	if interval > 0 {
+		b.wg.Add(1)
+		go b.loop()
+	}
+
+	return b
+}
+
+// Add starts watching a directory.
+func (b *PollingBackend) Add(dir string) error {
+	files, err := readDir(dir)
+	if err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Keep the current state if directory is already watched.
+	if _, ok := b.dirs[dir]; !ok {
+		b.dirs[dir] = files
+	}
+
+	return nil
+}
+
+// Remove stops watching a directory.
+func (b *PollingBackend) Remove(dir string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.dirs[dir]; !ok {
+		return fmt.Errorf("Directory not watched.")
+	}
+
+	delete(b.dirs, dir)
+	return nil
+}
+
+// Events returns the file events channel.
+func (b *PollingBackend) Events() <-chan WatchEvent {
+	return b.events
+}
+
+// Errors returns the errors channel.
+func (b *PollingBackend) Errors() <-chan error {
+	return b.errors
+}
+
+// Close removes all watches and closes the events and errors channels.
+func (b *PollingBackend) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+
+		// Wait for the polling loop and running polls.
+		b.wg.Wait()
+		b.poll.Lock()
+		defer b.poll.Unlock()
+
+		close(b.events)
+		close(b.errors)
+	})
+
+	return nil
+}
+
+// Poll compares the watched directories with their last known state, and
+// sends events for created, modified and removed files, Poll blocks until
+// all events are received.
+func (b *PollingBackend) Poll() {
+	// Lock:
+	// 1. sending on events and errors channels.
+	b.poll.Lock()
+	defer b.poll.Unlock()
+
+	select {
+	case <-b.done:
+		return
+	default:
+	}
+
+	events, errors := b.scan()
+
+	for _, e := range events {
+		select {
+		case b.events <- e:
+		case <-b.done:
+			return
+		}
+	}
+
+	for _, err := range errors {
+		select {
+		case b.errors <- err:
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// scan returns the events found comparing the watched directories with
+// their last known state.
+func (b *PollingBackend) scan() (events []WatchEvent, errors []error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Scan directories in a stable order.
+	dirs := make([]string, 0, len(b.dirs))
+	for dir := range b.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		files, err := readDir(dir)
+
+		// A removed directory is no longer watched.
+		if os.IsNotExist(err) {
+			events = append(events, compareDir(dir, b.dirs[dir], nil, &b.cookie)...)
+			events = append(events, WatchEvent{Name: dir, Op: Remove})
+
+			delete(b.dirs, dir)
+			continue
+		}
+
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+
+		events = append(events, compareDir(dir, b.dirs[dir], files, &b.cookie)...)
+		b.dirs[dir] = files
+	}
+
+	return
+}
+
+// loop polls the watched directories every interval.
+func (b *PollingBackend) loop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.Poll()
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// compareDir returns the events found comparing two states of a directory,
+// renamed files get a new cookie from the cookie counter.
+func compareDir(dir string, before map[string]fileState, after map[string]fileState, cookie *uint32) (events []WatchEvent) {
+	// Get all file names in a stable order.
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// Files removed and created with the same inode were renamed, rename
+	// events are sent before the create events of the new names.
+	created := make(map[uint64]string)
+	for name, state := range after {
+		if _, ok := before[name]; !ok && state.inode != 0 {
+			created[state.inode] = name
+		}
+	}
+
+	cookies := make(map[string]uint32)
+	for _, name := range names {
+		old, existed := before[name]
+		if _, exists := after[name]; !existed || exists || old.inode == 0 {
+			continue
+		}
+
+		if newName, ok := created[old.inode]; ok {
+			*cookie++
+			cookies[name] = *cookie
+			cookies[newName] = *cookie
+
+			events = append(events, WatchEvent{Name: pollName(dir, name), Op: Rename, Cookie: *cookie})
+		}
+	}
+
+	for _, name := range names {
+		var op Op
+
+		old, existed := before[name]
+		state, exists := after[name]
+
+		switch {
+		case !exists && cookies[name] != 0:
+			// The rename event was already sent.
+			continue
+		case !existed:
+			op = Create
+		case !exists:
+			op = Remove
+		case old.inode != state.inode:
+			// The file was replaced.
+			op = Create
+		case !state.mode.IsDir() && (old.size != state.size || !old.modTime.Equal(state.modTime)):
+			op = Write
+		case old.mode != state.mode:
+			op = Chmod
+		default:
+			continue
+		}
+
+		events = append(events, WatchEvent{Name: pollName(dir, name), Op: op, Cookie: cookies[name]})
+	}
+
+	return
+}
+
+// pollName returns the event name of a file in a watched directory.
+func pollName(dir string, name string) string {
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		return dir + name
+	}
+
+	return dir + string(filepath.Separator) + name
+}
+
+// readDir returns the state of the files in a directory.
+func readDir(dir string) (map[string]fileState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]fileState, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// File removed while reading the directory.
+			continue
+		}
+
+		files[entry.Name()] = fileState{
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			mode:    info.Mode(),
+			inode:   inode(info),
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPollingBackend(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_poll.txt")
+
+	// watch temporary dir using a polling backend
+	b := NewPollingBackend(0)
+	if err := o.SetBackend(b); err != nil {
+		t.Error("error setting a polling backend.")
+	}
+	o.Watch([]string{tmpfn})
+	defer o.Close()
+
+	if err := o.SetBackend(NewPollingBackend(0)); err == nil {
+		t.Error("no error setting a backend on a watching Observer.")
+	}
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	ops := []struct {
+		op     Op
+		change func() error
+	}{
+		{Create, func() error { return ioutil.WriteFile(tmpfn, []byte("content"), 0666) }},
+		{Write, func() error { return ioutil.WriteFile(tmpfn, []byte("new content"), 0666) }},
+		{Remove, func() error { return os.Remove(tmpfn) }},
+	}
+
+	for _, c := range ops {
+		if err := c.change(); err != nil {
+			t.Errorf("error changing temp file: %v.", err)
+		}
+		b.Poll()
+
+		select {
+		case e := <-events:
+			if e.Name != tmpfn || e.Op != c.op {
+				t.Errorf("error polling %s event, received %v.", c.op, e)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("error polling %s event.", c.op)
+		}
+	}
+}
+
+func TestCompareDir(t *testing.T) {
+	now := time.Now()
+	before := map[string]fileState{
+		"same":     {size: 1, modTime: now, mode: 0644, inode: 1},
+		"write":    {size: 1, modTime: now, mode: 0644, inode: 2},
+		"chmod":    {size: 1, modTime: now, mode: 0644, inode: 3},
+		"replaced": {size: 1, modTime: now, mode: 0644, inode: 4},
+		"removed":  {size: 1, modTime: now, mode: 0644, inode: 5},
+		"old":      {size: 1, modTime: now, mode: 0644, inode: 8},
+	}
+	after := map[string]fileState{
+		"same":     {size: 1, modTime: now, mode: 0644, inode: 1},
+		"write":    {size: 1, modTime: now.Add(time.Second), mode: 0644, inode: 2},
+		"chmod":    {size: 1, modTime: now, mode: 0600, inode: 3},
+		"replaced": {size: 1, modTime: now, mode: 0644, inode: 6},
+		"created":  {size: 1, modTime: now, mode: 0644, inode: 7},
+		"new":      {size: 1, modTime: now, mode: 0644, inode: 8},
+	}
+
+	expected := map[string]Op{
+		"write":    Write,
+		"chmod":    Chmod,
+		"replaced": Create,
+		"removed":  Remove,
+		"created":  Create,
+		"old":      Rename,
+		"new":      Create,
+	}
+
+	var cookie uint32
+	events := compareDir("dir", before, after, &cookie)
+	if len(events) != len(expected) {
+		t.Errorf("error comparing directory states, received %v.", events)
+	}
+
+	for _, e := range events {
+		if expected[filepath.Base(e.Name)] != e.Op {
+			t.Errorf("error comparing directory states, received %v.", e)
+		}
+	}
+
+	// Renamed files are sent first, with the same cookie.
+	if len(events) > 0 && (events[0].Name != filepath.Join("dir", "old") || events[0].Cookie != 1) {
+		t.Errorf("error comparing renamed file, received %v.", events[0])
+	}
+	for _, e := range events[1:] {
+		if (filepath.Base(e.Name) == "new") != (e.Cookie == 1) {
+			t.Errorf("error comparing renamed file cookie, received %v.", e)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"time"
+)
+
+// Clock is the time source used by the observer timers, tests can use a
+// virtual clock to advance time synchronously.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// AfterFunc waits for the duration to elapse and then calls f.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer created by a Clock.
+type Timer interface {
+	// Stop prevents the timer from firing, it returns false if the timer
+	// already fired or was stopped.
+	Stop() bool
+}
+
+// realClock is a Clock using the time package.
+type realClock struct{}
+
+// Now returns the current time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc waits for the duration to elapse and then calls f.
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// SetClock set the clock used by the observer timers, the default clock is
+// using the time package.
+func (o *Observer) SetClock(c Clock) {
+	o.init()
+
+	// Lock:
+	// 1. operations on observer timers.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.clock = c
+}
@@ -0,0 +1,226 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pendingSave is the file operations received for a file, waiting for the
+// file to be quiet.
+type pendingSave struct {
+	ops     Op
+	last    Op   // The last of Create and Remove received, Rename is a Remove.
+	existed bool // The file existed before the first event.
+	timer   Timer
+}
+
+// SetSaveCoalescing set the save coalescing quiet duration, editors and
+// configuration tools often save a file by writing a temporary file,
+// removing the original file and renaming the temporary file into place,
+// save coalescing report such a burst of events as one logical change.
+//
+// The file operations of a watched file are collected until no event is
+// received for the file during the quiet duration, then one event is sent:
+// Remove if the last Create or Remove operation is a Remove, Create if the
+// file did not exist before the first event, Chmod if only the file mode
+// changed, o/w Modified. Renamed files are coalesced using the new and the
+// old file names. Zero quiet duration disable save coalescing.
+//
+// Files already in the watched directories are known to exist, so
+// SetSaveCoalescing should be called before Watch.
+func (o *Observer) SetSaveCoalescing(quiet time.Duration) {
+	o.init()
+
+	// Lock:
+	// 1. operations on the saves map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.saveQuiet = quiet
+	o.saveFiles = make(map[string]bool)
+}
+
+// coalesceSave collects the file operations of a watched file, it returns
+// a boolean asserting whether the event is held until the file is quiet.
+func (o *Observer) coalesceSave(e WatchEvent) bool {
+	// Lock:
+	// 1. operations on the saves map.
+	// 2. operations using the watchPatterns set (matchName).
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.saveQuiet == 0 {
+		return false
+	}
+
+	// Tracked renames remove the old file and create the new file.
+	if e.OldName != "" {
+		held := o.collectSave(e.OldName, Rename)
+		return o.collectSave(e.Name, Create) || held
+	}
+
+	return o.collectSave(e.Name, e.Op)
+}
+
+// collectSave collects a file operation of a watched file, it returns
+// a boolean asserting whether the file is watched.
+func (o *Observer) collectSave(name string, op Op) bool {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using collectSave must be locked
+	// for operations using o.saves and o.watchPatterns.
+
+	// Directories and files that are not watched are not coalesced.
+	if _, isDir := o.watchDirs[filepath.Clean(name)]; isDir || !o.matchName(name, AllOps) {
+		return false
+	}
+
+	if o.saves == nil {
+		o.saves = make(map[string]*pendingSave)
+	}
+
+	// Each event restarts the file quiet duration.
+	p, ok := o.saves[name]
+	if ok {
+		p.timer.Stop()
+	} else {
+		p = &pendingSave{existed: o.saveFiles[filepath.Clean(name)]}
+		o.saves[name] = p
+	}
+
+	p.ops |= op
+	if op&(Create|Remove|Rename) != 0 {
+		p.last = op & (Create | Remove | Rename)
+	}
+	p.timer = o.clock.AfterFunc(o.saveQuiet, func() {
+		o.expireSave(name, p)
+	})
+
+	return true
+}
+
+// expireSave send the logical change event of a quiet file.
+func (o *Observer) expireSave(name string, p *pendingSave) {
+	// Lock:
+	// 1. operations on the saves map.
+	o.mutex.Lock()
+	found := o.saves[name] == p
+	if found {
+		delete(o.saves, name)
+	}
+	o.mutex.Unlock()
+
+	// The file was already flushed, or has a newer pending save.
+	if !found {
+		return
+	}
+
+	o.handleWatchEvent(o.saveEvent(name, p))
+}
+
+// flushSaves send all pending saves now.
+func (o *Observer) flushSaves() {
+	// Lock:
+	// 1. operations on the saves map.
+	o.mutex.Lock()
+	saves := o.saves
+	o.stopSaves()
+	o.mutex.Unlock()
+
+	for name, p := range saves {
+		o.handleWatchEvent(o.saveEvent(name, p))
+	}
+}
+
+// stopSaves stop the pending saves timers, and drop pending saves.
+func (o *Observer) stopSaves() {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using stopSaves must be locked
+	// for operations using o.saves.
+	for _, p := range o.saves {
+		p.timer.Stop()
+	}
+
+	o.saves = nil
+}
+
+// saveEvent returns the logical change event of a file, and records
+// whether the file exists.
+func (o *Observer) saveEvent(name string, p *pendingSave) WatchEvent {
+	removed := p.last&(Remove|Rename) != 0
+
+	// The order of operations received in one event is unknown.
+	if p.last&Create != 0 && removed {
+		_, err := os.Stat(name)
+		removed = err != nil
+	}
+
+	// Lock:
+	// 1. operations on the known files map.
+	o.mutex.Lock()
+	if o.saveFiles != nil {
+		if removed {
+			delete(o.saveFiles, filepath.Clean(name))
+		} else {
+			o.saveFiles[filepath.Clean(name)] = true
+		}
+	}
+	o.mutex.Unlock()
+
+	if removed {
+		return WatchEvent{Name: name, Op: Remove}
+	}
+
+	// The file did not exist before the first event.
+	if !p.existed && p.ops&Create != 0 {
+		return WatchEvent{Name: name, Op: Create}
+	}
+
+	// Only the file mode changed.
+	if p.ops == Chmod {
+		return WatchEvent{Name: name, Op: Chmod}
+	}
+
+	return WatchEvent{Name: name, Op: Modified}
+}
+
+// saveDir records the watched files in a directory as known to exist.
+func (o *Observer) saveDir(dir string) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using saveDir must be locked
+	// for operations using o.saveFiles and o.watchPatterns.
+	if o.saveQuiet == 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := pollName(dir, entry.Name())
+		if !entry.IsDir() && o.matchName(name, AllOps) {
+			o.saveFiles[filepath.Clean(name)] = true
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetSaveCoalescing(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_save.conf")
+	backup := filepath.Join(dir, "test_save.conf~")
+	if err := ioutil.WriteFile(tmpfn, []byte("content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+
+	// watch temporary file saves
+	o.SetSaveCoalescing(200 * time.Millisecond)
+	o.Watch([]string{tmpfn})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	// Save the file the way editors do, rename the original file,
+	// write a new file and remove the renamed file.
+	if err := os.Rename(tmpfn, backup); err != nil {
+		t.Error("error renaming temp file.")
+	}
+	if err := ioutil.WriteFile(tmpfn, []byte("new content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	if err := os.Remove(backup); err != nil {
+		t.Error("error removing temp file.")
+	}
+
+	select {
+	case e := <-events:
+		if e.Name != tmpfn || e.Op != Modified {
+			t.Errorf("error coalescing file save, received %v.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error coalescing file save.")
+	}
+
+	// Only one event is sent.
+	select {
+	case e := <-events:
+		t.Errorf("error coalescing file save, received another event %v.", e)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestSaveCoalescingCreate(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_new.conf")
+
+	// watch new files
+	o.SetSaveCoalescing(200 * time.Millisecond)
+	o.Watch([]string{filepath.Join(dir, "*.conf")})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	// A new file is created and written.
+	if err := ioutil.WriteFile(tmpfn, []byte("content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+
+	select {
+	case e := <-events:
+		if e.Name != tmpfn || e.Op != Create {
+			t.Errorf("error coalescing new file, received %v.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error coalescing new file.")
+	}
+}
+
+func TestSaveCoalescingRenameOver(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_save.conf")
+	temp := filepath.Join(dir, "test_save.tmp")
+	if err := ioutil.WriteFile(tmpfn, []byte("content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+
+	// watch temporary file saves
+	o.SetSaveCoalescing(200 * time.Millisecond)
+	o.Watch([]string{tmpfn})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	// Save the file the way atomic write libraries do, write a temporary
+	// file and rename it over the original file.
+	if err := ioutil.WriteFile(temp, []byte("new content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	if err := os.Rename(temp, tmpfn); err != nil {
+		t.Error("error renaming temp file.")
+	}
+
+	select {
+	case e := <-events:
+		if e.Name != tmpfn || e.Op != Modified {
+			t.Errorf("error coalescing file save, received %v.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error coalescing file save.")
+	}
+}
+
+func TestSaveCoalescingRenameTracking(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_save.conf")
+	backup := filepath.Join(dir, "test_save.conf~")
+	if err := ioutil.WriteFile(tmpfn, []byte("content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+
+	// watch temporary file saves, merging renamed files
+	o.SetSaveCoalescing(200 * time.Millisecond)
+	o.SetRenameTracking(100 * time.Millisecond)
+	o.Watch([]string{tmpfn})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	// Save the file the way editors do, the renamed file is merged into
+	// one event with the backup name, and the original name as OldName.
+	if err := os.Rename(tmpfn, backup); err != nil {
+		t.Error("error renaming temp file.")
+	}
+	if err := ioutil.WriteFile(tmpfn, []byte("new content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	if err := os.Remove(backup); err != nil {
+		t.Error("error removing temp file.")
+	}
+
+	select {
+	case e := <-events:
+		if e.Name != tmpfn || e.Op != Modified {
+			t.Errorf("error coalescing file save, received %v.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error coalescing file save.")
+	}
+
+	// Only one event is sent.
+	select {
+	case e := <-events:
+		t.Errorf("error coalescing file save, received another event %v.", e)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DebounceMode is the event buffer debounce policy.
+type DebounceMode int
+
+// These are the event buffer debounce policies.
+const (
+	// FixedWindow send buffered events one buffer duration after the first
+	// event, this is the default mode.
+	FixedWindow DebounceMode = iota
+
+	// Trailing send buffered events after no event was received during the
+	// buffer duration, each event restarts the buffer duration.
+	Trailing
+
+	// Leading send the first event immediately, and drop events received
+	// until no event was received during the buffer duration.
+	Leading
+)
+
+// KeyFunc is the function type returning the buffer key of an event.
+type KeyFunc func(event interface{}) string
+
+// PathKey is a KeyFunc buffering file events per file path, other events
+// share one buffer.
+func PathKey(event interface{}) string {
+	if e, ok := event.(WatchEvent); ok {
+		return filepath.Clean(e.Name)
+	}
+
+	return ""
+}
+
+// debounce is the event buffer of one key.
+type debounce struct {
+	events []message
+	timer  Timer
+	first  time.Time
+}
+
+// SetDebounceMode set the event buffer debounce mode, FixedWindow,
+// Trailing or Leading.
+func (o *Observer) SetDebounceMode(mode DebounceMode) {
+	o.init()
+
+	// Lock:
+	// 1. operations on buffers map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.debounceMode = mode
+}
+
+// SetMaxWait set the maximum time events are delayed in Trailing mode, and
+// the maximum time events are dropped in Leading mode, so continuous
+// activity is still sent periodically. Zero duration disable the maximum
+// wait.
+func (o *Observer) SetMaxWait(d time.Duration) {
+	o.init()
+
+	// Lock:
+	// 1. operations on buffers map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.maxWait = d
+}
+
+// SetBufferKey set the event buffer key function, when set each key has
+// it's own event buffer and timer, and is sent independently of other
+// keys, repeated events of the same key are sent once. The default key
+// function is nil, all events share one buffer.
+func (o *Observer) SetBufferKey(k KeyFunc) {
+	o.init()
+
+	// Lock:
+	// 1. operations on buffers map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.bufferKey = k
+}
+
+// bufferEvent add an event to the event buffer of the event key.
+func (o *Observer) bufferEvent(m message) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using bufferEvent must be locked
+	// for operations using o.buffers.
+	key := ""
+	if o.bufferKey != nil {
+		key = o.bufferKey(m.event)
+	}
+
+	if o.buffers == nil {
+		o.buffers = make(map[string]*debounce)
+	}
+
+	b, ok := o.buffers[key]
+	if !ok {
+		b = &debounce{first: o.clock.Now()}
+		o.buffers[key] = b
+	}
+
+	if o.debounceMode == Leading {
+		o.leadingEvent(key, b, m, !ok)
+		return
+	}
+
+	// Replace repeated events of a key with the latest event.
+	repeated := false
+	if o.bufferKey != nil {
+		for i, buffered := range b.events {
+			if buffered.topic == m.topic && sameEvent(buffered.event, m.event) {
+				b.events[i] = m
+				repeated = true
+				break
+			}
+		}
+	}
+
+	// If this is the first event, set a timeout function.
+	if !repeated {
+		b.events = append(b.events, m)
+
+		if len(b.events) == 1 {
+			o.startBufferTimer(key, b, o.bufferDuration)
+			return
+		}
+	}
+
+	// In trailing mode each event restarts the timeout, but events are not
+	// delayed more than the maximum wait.
+	if o.debounceMode == Trailing {
+		b.timer.Stop()
+
+		wait := o.bufferDuration
+		if o.maxWait > 0 {
+			if left := o.maxWait - o.clock.Now().Sub(b.first); left < wait {
+				wait = left
+			}
+		}
+
+		if wait <= 0 {
+			o.flushKey(key)
+			return
+		}
+
+		o.startBufferTimer(key, b, wait)
+	}
+}
+
+// leadingEvent send the first event of a key immediately, and drop the
+// following events until the key is quiet.
+func (o *Observer) leadingEvent(key string, b *debounce, m message, first bool) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using leadingEvent must be locked
+	// for operations using o.listeners and o.buffers.
+	if !first {
+		b.timer.Stop()
+	}
+
+	// Send the first event, and events received after the maximum wait.
+	now := o.clock.Now()
+	if first || (o.maxWait > 0 && now.Sub(b.first) >= o.maxWait) {
+		b.first = now
+		o.sendEvents([]message{m})
+	}
+
+	// Each event restarts the quiet duration.
+	o.startBufferTimer(key, b, o.bufferDuration)
+}
+
+// startBufferTimer set a timeout function sending the events of a key.
+func (o *Observer) startBufferTimer(key string, b *debounce, d time.Duration) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using startBufferTimer must be locked
+	// for operations using o.buffers.
+	b.timer = o.clock.AfterFunc(d, func() {
+		// Lock:
+		// 1. operations on listeners array (sendEvents).
+		// 2. operations on buffers map.
+		o.mutex.Lock()
+		defer o.unlock()
+
+		// The buffer was already flushed.
+		if o.buffers[key] != b {
+			return
+		}
+
+		o.flushKey(key)
+	})
+}
+
+// flushKey send all events in the event buffer of a key.
+func (o *Observer) flushKey(key string) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using flushKey must be locked
+	// for operations using o.listeners and o.buffers.
+	b := o.buffers[key]
+	delete(o.buffers, key)
+
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+
+	o.sendEvents(b.events)
+}
+
+// flushBuffer send all events in all event buffers.
+func (o *Observer) flushBuffer() {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using flushBuffer must be locked
+	// for operations using o.listeners and o.buffers.
+	keys := make([]string, 0, len(o.buffers))
+	for key := range o.buffers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		o.flushKey(key)
+	}
+}
+
+// stopBuffer stop the event buffers timers, and drop buffered events.
+func (o *Observer) stopBuffer() {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using stopBuffer must be locked
+	// for operations using o.buffers.
+	for _, b := range o.buffers {
+		if b.timer != nil {
+			b.timer.Stop()
+		}
+	}
+
+	o.buffers = nil
+}
+
+// sameEvent returns a boolean asserting whether two events are the same
+// event, file events are the same if they have the same names and
+// operation.
+func sameEvent(a interface{}, b interface{}) (same bool) {
+	if ea, ok := a.(WatchEvent); ok {
+		eb, ok := b.(WatchEvent)
+		return ok && ea.Name == eb.Name && ea.OldName == eb.OldName && ea.Op == eb.Op
+	}
+
+	// Events that can not be compared are not the same.
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+
+	return a == b
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"testing"
+)
+
+func TestSameEvent(t *testing.T) {
+	cases := []struct {
+		a    interface{}
+		b    interface{}
+		same bool
+	}{
+		{"a", "a", true},
+		{"a", "b", false},
+		{1, "1", false},
+		{WatchEvent{Name: "a", Op: Write}, WatchEvent{Name: "a", Op: Write, Info: &FileInfo{}}, true},
+		{WatchEvent{Name: "a", Op: Write}, WatchEvent{Name: "a", Op: Create}, false},
+		{[]int{1}, []int{1}, false},
+	}
+
+	for _, c := range cases {
+		if sameEvent(c.a, c.b) != c.same {
+			t.Errorf("error comparing events %v and %v.", c.a, c.b)
+		}
+	}
+}
+
+func TestPathKey(t *testing.T) {
+	if PathKey(WatchEvent{Name: "./a.conf"}) != "a.conf" || PathKey("a.conf") != "" {
+		t.Error("error getting event path key.")
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeliveryMode is the policy used to run listeners.
+type DeliveryMode int
+
+// These are the listener delivery modes.
+const (
+	// Concurrent run each listener call in a new goroutine, listener calls
+	// may run in parallel and out of order, this is the default mode.
+	Concurrent DeliveryMode = iota
+
+	// Sequential run the calls of each listener in order, one at a time,
+	// different listeners still run in parallel.
+	Sequential
+)
+
+// queue is the pending events of a listener in Sequential mode.
+type queue struct {
+	events []interface{}
+}
+
+// SetDeliveryMode set the listener delivery mode, Concurrent or Sequential,
+// it will return an error if a dispatch pool is set and the delivery mode
+// is Sequential.
+func (o *Observer) SetDeliveryMode(mode DeliveryMode) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on the listener queues map.
+	// 2. operations on the dispatch pool.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if mode == Sequential && o.pool != nil {
+		return fmt.Errorf("Dispatch pool can not be used in Sequential delivery mode.")
+	}
+
+	o.deliveryMode = mode
+	return nil
+}
+
+// enqueue add an event to the listener queue, and start the listener queue
+// goroutine if it is not running.
+func (o *Observer) enqueue(ctx context.Context, l listener, event interface{}) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using enqueue must be locked
+	// for operations using o.queues.
+	if o.queues == nil {
+		o.queues = make(map[uint64]*queue)
+	}
+
+	q, running := o.queues[l.id]
+	if !running {
+		q = &queue{}
+		o.queues[l.id] = q
+	}
+	q.events = append(q.events, event)
+
+	if !running {
+		go o.runQueue(ctx, l, q)
+	}
+}
+
+// runQueue runs the listener with the queued events in order, the queue
+// goroutine exits when the queue is empty.
+func (o *Observer) runQueue(ctx context.Context, l listener, q *queue) {
+	for {
+		// Lock:
+		// 1. operations on the listener queues map.
+		o.mutex.Lock()
+		if len(q.events) == 0 {
+			delete(o.queues, l.id)
+			o.mutex.Unlock()
+
+			return
+		}
+
+		event := q.events[0]
+		q.events = q.events[1:]
+		o.mutex.Unlock()
+
+		o.run(ctx, l, event)
+		o.done(l)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetDeliveryMode(t *testing.T) {
+	var o Observer
+	var active int32
+
+	// The listener is not locked, events are received in order and one
+	// at a time.
+	received := make([]int, 0)
+
+	o.SetDeliveryMode(Sequential)
+	o.Open()
+
+	o.AddListener(func(e interface{}) {
+		if atomic.AddInt32(&active, 1) != 1 {
+			t.Error("error running listener calls sequentially.")
+		}
+		defer atomic.AddInt32(&active, -1)
+
+		time.Sleep(time.Millisecond)
+		received = append(received, e.(int))
+	})
+
+	for i := 0; i < 20; i++ {
+		o.Emit(i)
+	}
+
+	// Wait for all queued events.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.Shutdown(ctx); err != nil {
+		t.Errorf("error waiting for queued events: %v.", err)
+	}
+
+	if len(received) != 20 {
+		t.Fatalf("error receiving queued events, received %v.", received)
+	}
+	for i, e := range received {
+		if e != i {
+			t.Fatalf("error receiving events in order, received %v.", received)
+		}
+	}
+}
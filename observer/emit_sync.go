@@ -0,0 +1,151 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// EmitError is returned by EmitSync when listeners failed.
+type EmitError struct {
+	Errors []error // The listeners errors as *ListenerError, and the context error.
+}
+
+// Error returns the emit error message.
+func (e *EmitError) Error() string {
+	return fmt.Sprintf("%d listeners failed: %v", len(e.Errors), e.Errors)
+}
+
+// Unwrap returns the listeners errors.
+func (e *EmitError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is returns a boolean asserting whether one of the listeners errors
+// matches target, errors.Is uses Unwrap() []error only since go 1.20.
+func (e *EmitError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first listeners error that matches target, errors.As uses
+// Unwrap() []error only since go 1.20.
+func (e *EmitError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// syncCall is the event of a listener call dispatched by EmitSync, the
+// listener result is sent to the results channel.
+type syncCall struct {
+	ctx     context.Context
+	event   interface{}
+	results chan error
+}
+
+// EmitSync emit an event and wait for the listeners to finish, listeners
+// are called without buffering, using the delivery mode and the dispatch
+// pool, and receive a context cancelled when ctx is done or the observer
+// is closed.
+//
+// If listeners failed, EmitSync returns an *EmitError with a *ListenerError
+// for each failed listener, listener calls dropped by the dispatch pool
+// fail with ErrDropped, if ctx is done before the listeners finished
+// the context error is added to the returned errors. Listener errors are
+// not reported to the error hook. It will return ErrNotOpen if the
+// observer is not opened and ErrClosed if the observer is closed.
+func (o *Observer) EmitSync(ctx context.Context, event interface{}) error {
+	return o.EmitTopicSync(ctx, "", event)
+}
+
+// EmitTopicSync emit an event on a topic and wait for the listeners
+// subscribed to a matching topic to finish, same as EmitSync.
+func (o *Observer) EmitTopicSync(ctx context.Context, topic string, event interface{}) error {
+	o.init()
+
+	// Cancel the listeners context when ctx is done or the observer
+	// is closed.
+	lctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Lock:
+	// 1. operations on observer state.
+	// 2. operations on listeners array.
+	// 3. operations on running listeners map (dispatch).
+	o.mutex.Lock()
+	switch o.state {
+	case StateNew:
+		o.mutex.Unlock()
+		return ErrNotOpen
+	case StateClosing, StateClosed:
+		o.mutex.Unlock()
+		return ErrClosed
+	}
+
+	listeners := make([]listener, 0, len(o.listeners))
+	for _, l := range o.listeners {
+		if matchTopic(l.topic, topic) && l.accepts(event) {
+			listeners = append(listeners, l)
+		}
+	}
+
+	c := &syncCall{ctx: lctx, event: event, results: make(chan error, len(listeners))}
+	for _, l := range listeners {
+		o.dispatch(l, c)
+	}
+	closed := o.ctx
+	o.unlock()
+
+	go func() {
+		select {
+		case <-closed.Done():
+			cancel()
+		case <-lctx.Done():
+		}
+	}()
+
+	// Wait for the listeners to finish.
+	errs := make([]error, 0)
+	for range listeners {
+		select {
+		case err := <-c.results:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			return &EmitError{Errors: append(errs, ctx.Err())}
+		}
+	}
+
+	if len(errs) > 0 {
+		return &EmitError{Errors: errs}
+	}
+
+	return nil
+}
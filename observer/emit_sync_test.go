@@ -0,0 +1,187 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEmitSync(t *testing.T) {
+	var o Observer
+	var calls int32
+
+	ctx := context.Background()
+	if err := o.EmitSync(ctx, "event"); err != ErrNotOpen {
+		t.Error("error emitting on a new Observer.")
+	}
+
+	o.Open()
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&calls, 1)
+	})
+	o.AddErrorListener(func(e interface{}) error {
+		atomic.AddInt32(&calls, 1)
+		return fmt.Errorf("invalid %v", e)
+	})
+	failing := o.AddListener(func(e interface{}) {
+		atomic.AddInt32(&calls, 1)
+		panic("bad listener")
+	})
+
+	// All listeners finished when EmitSync returns.
+	err := o.EmitSync(ctx, "event")
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Error("error waiting for listeners.")
+	}
+
+	var emitErr *EmitError
+	if !errors.As(err, &emitErr) || len(emitErr.Errors) != 2 {
+		t.Fatalf("error aggregating listener errors, received %v.", err)
+	}
+
+	var panicked bool
+	for _, err := range emitErr.Errors {
+		var le *ListenerError
+		if errors.As(err, &le) && le.ID == failing.ID() {
+			panicked = true
+		}
+	}
+	if !panicked {
+		t.Errorf("error reporting listener panic, received %v.", err)
+	}
+}
+
+func TestEmitSyncContext(t *testing.T) {
+	var o Observer
+
+	o.Open()
+	defer o.Close()
+
+	o.AddContextListener(func(ctx context.Context, e interface{}) {
+		<-ctx.Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var emitErr *EmitError
+	err := o.EmitSync(ctx, "event")
+	if !errors.As(err, &emitErr) || len(emitErr.Errors) != 1 || emitErr.Errors[0] != context.DeadlineExceeded {
+		t.Errorf("error waiting for listeners until ctx is done, received %v.", err)
+	}
+}
+
+func TestEmitSyncSequential(t *testing.T) {
+	var o Observer
+	var running, overlaps int32
+
+	o.SetDeliveryMode(Sequential)
+	o.Open()
+	defer o.Close()
+
+	events := make(chan interface{}, 10)
+	o.AddListener(func(e interface{}) {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		events <- e
+	})
+
+	// Synchronous calls are queued after the emitted events.
+	for i := 0; i < 3; i++ {
+		o.Emit(i)
+	}
+	for {
+		o.mutex.Lock()
+		dispatched := o.dispatched
+		o.mutex.Unlock()
+		if dispatched == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.EmitSync(ctx, 3); err != nil {
+		t.Errorf("error emitting a synchronous event: %v.", err)
+	}
+
+	if atomic.LoadInt32(&overlaps) != 0 {
+		t.Error("error running a Sequential listener concurrently with itself.")
+	}
+	for i := 0; i < 4; i++ {
+		if e := <-events; e != i {
+			t.Errorf("error keeping the emit order, received %v.", e)
+		}
+	}
+}
+
+func TestEmitSyncDropped(t *testing.T) {
+	var o Observer
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+
+	o.SetDispatchPool(1, 1, DropNewest)
+	o.Open()
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		started <- struct{}{}
+		<-release
+	})
+
+	// The first event is running, the second is queued, and the
+	// synchronous call is dropped.
+	o.Emit(1)
+	<-started
+	o.Emit(2)
+	waitStats(t, &o, Stats{Dispatched: 2, Queued: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.EmitSync(ctx, 3); !errors.Is(err, ErrDropped) {
+		t.Errorf("error dropping a synchronous call, received %v.", err)
+	}
+	close(release)
+}
+
+func TestEmitErrorIs(t *testing.T) {
+	err := &EmitError{Errors: []error{&ListenerError{ID: 1, Err: ErrDropped}, context.Canceled}}
+
+	// The methods are used by errors.Is and errors.As before go 1.20.
+	if !err.Is(ErrDropped) || !err.Is(context.Canceled) || err.Is(ErrClosed) {
+		t.Error("error matching the listeners errors.")
+	}
+
+	var le *ListenerError
+	if !err.As(&le) || le.ID != 1 {
+		t.Error("error finding a listener error.")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// ErrorListener is the function type to run on events, returned errors are
+// reported to the observer error hook.
+type ErrorListener func(interface{}) error
+
+// ErrorHook is the function type receiving listener errors.
+type ErrorHook func(error)
+
+// PanicError is the error reported when a listener panics.
+type PanicError struct {
+	Value interface{} // The value passed to panic.
+	Stack []byte      // The listener stack trace.
+}
+
+// Error returns the panic error message.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Listener panic: %v", e.Value)
+}
+
+// ListenerError is the error reported when a listener fails.
+type ListenerError struct {
+	ID  uint64 // Subscription id of the failed listener.
+	Err error  // The listener error, or a *PanicError.
+}
+
+// Error returns the listener error message.
+func (e *ListenerError) Error() string {
+	return fmt.Sprintf("Listener %d: %v", e.ID, e.Err)
+}
+
+// Unwrap returns the listener error.
+func (e *ListenerError) Unwrap() error {
+	return e.Err
+}
+
+// AddErrorListener adds a listener function to run on event, the listener
+// function will recive the event object as argument, errors returned by the
+// listener are reported to the error hook set using OnError.
+func (o *Observer) AddErrorListener(l ErrorListener) Subscription {
+	return o.addErrorListener(AllTopics, func(ctx context.Context, e interface{}) error {
+		return l(e)
+	})
+}
+
+// OnError set the error hook, listener panics and errors returned by error
+// listeners are reported to the hook as *ListenerError, if no hook is set
+// errors are logged.
+func (o *Observer) OnError(hook ErrorHook) {
+	o.init()
+
+	// Lock:
+	// 1. operations on the error hook.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.onError = hook
+}
+
+// call runs a listener with an event, a listener panic is recovered and
+// returned as a *PanicError.
+func call(ctx context.Context, l listener, event interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return l.fn(ctx, event)
+}
+
+// reportError report a listener error to the error hook.
+func (o *Observer) reportError(err error) {
+	// Lock:
+	// 1. operations on the error hook.
+	o.mutex.Lock()
+	hook := o.onError
+	o.mutex.Unlock()
+
+	if hook == nil {
+		log.Printf("[Error] %v", err)
+		return
+	}
+
+	hook(err)
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOnError(t *testing.T) {
+	var o Observer
+
+	errs := make(chan error, 10)
+
+	o.Open()
+	defer o.Close()
+
+	o.OnError(func(err error) {
+		errs <- err
+	})
+
+	panicking := o.AddListener(func(e interface{}) {
+		panic("bad listener")
+	})
+	o.Emit("event")
+
+	select {
+	case err := <-errs:
+		var le *ListenerError
+		var pe *PanicError
+		if !errors.As(err, &le) || le.ID != panicking.ID() {
+			t.Errorf("error reporting listener id, received %v.", err)
+		}
+		if !errors.As(err, &pe) || pe.Value != "bad listener" || len(pe.Stack) == 0 {
+			t.Errorf("error reporting listener panic, received %v.", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error recovering listener panic.")
+	}
+	panicking.Unsubscribe()
+
+	// Errors returned by error listeners are reported.
+	failed := fmt.Errorf("failed")
+	o.AddErrorListener(func(e interface{}) error {
+		return failed
+	})
+	o.Emit("event")
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, failed) {
+			t.Errorf("error reporting listener error, received %v.", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error reporting listener error.")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// SetContentHash set the maximum size of hashed files, when content hash is
+// set watched files are hashed using sha256, Write and Modified events are
+// sent only if the file content changed, and file events carry the file
+// OldHash and Hash. Files larger than maxSize are not hashed, and their
+// events are always sent. Zero maxSize disable content hash.
+//
+// Files already in the watched directories are hashed by Watch, so
+// SetContentHash should be called before Watch.
+func (o *Observer) SetContentHash(maxSize int64) {
+	o.init()
+
+	// Lock:
+	// 1. operations on the hashes map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.hashMax = maxSize
+	o.hashes = make(map[string]string)
+}
+
+// hashContent set the old and new content hash of a file event, it returns
+// a boolean asserting whether the event should be sent.
+func (o *Observer) hashContent(e *WatchEvent) bool {
+	// Lock:
+	// 1. operations on the hashes map.
+	// 2. operations using the watchPatterns set (matchName).
+	o.mutex.Lock()
+	maxSize := o.hashMax
+	watched := maxSize > 0 && o.matchName(e.Name, AllOps)
+	oldName := filepath.Clean(e.Name)
+	if e.OldName != "" {
+		oldName = filepath.Clean(e.OldName)
+	}
+	e.OldHash = o.hashes[oldName]
+	o.mutex.Unlock()
+
+	if !watched {
+		return true
+	}
+
+	// Removed files have no content.
+	if e.Op&(Create|Write|Modified) == 0 && e.OldName == "" {
+		if e.Op&(Remove|Rename) != 0 {
+			o.setHash(oldName, "")
+		} else {
+			e.Hash = e.OldHash
+		}
+
+		return true
+	}
+
+	// Files that can not be hashed are always sent.
+	e.Hash, _ = hashFile(e.Name, maxSize)
+	if e.OldName != "" {
+		o.setHash(oldName, "")
+	}
+	o.setHash(filepath.Clean(e.Name), e.Hash)
+
+	unchanged := e.Hash != "" && e.Hash == e.OldHash
+	return !(unchanged && e.Op&Create == 0 && e.Op&(Write|Modified) != 0)
+}
+
+// setHash set the content hash of a file, empty hash removes the file.
+func (o *Observer) setHash(name string, hash string) {
+	// Lock:
+	// 1. operations on the hashes map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.hashes == nil {
+		return
+	}
+
+	if hash == "" {
+		delete(o.hashes, name)
+		return
+	}
+
+	o.hashes[name] = hash
+}
+
+// hashDir hash the watched files in a directory.
+func (o *Observer) hashDir(dir string) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using hashDir must be locked
+	// for operations using o.hashes and o.watchPatterns.
+	if o.hashMax == 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := pollName(dir, entry.Name())
+		if entry.IsDir() || !o.matchName(name, AllOps) {
+			continue
+		}
+
+		if hash, err := hashFile(name, o.hashMax); err == nil {
+			o.hashes[filepath.Clean(name)] = hash
+		}
+	}
+}
+
+// hashFile returns the hex encoded sha256 hash of a file content, it will
+// return an error for files larger than maxSize.
+func hashFile(name string, maxSize int64) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(f, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if n > maxSize {
+		return "", fmt.Errorf("File too large to hash.")
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
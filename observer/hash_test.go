@@ -0,0 +1,79 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetContentHash(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_hash.txt")
+	if err := ioutil.WriteFile(tmpfn, []byte("content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	hash, _ := hashFile(tmpfn, 1024)
+
+	// watch temporary file content using a polling backend
+	b := NewPollingBackend(0)
+	o.SetBackend(b)
+	o.SetContentHash(1024)
+	o.Watch([]string{tmpfn})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	// Touch the file without changing the content.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(tmpfn, later, later); err != nil {
+		t.Error("error touching temp file.")
+	}
+	b.Poll()
+
+	// Change the file content.
+	if err := ioutil.WriteFile(tmpfn, []byte("new content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	b.Poll()
+
+	select {
+	case e := <-events:
+		if e.Op != Write || e.OldHash != hash || e.Hash == "" || e.Hash == hash {
+			t.Errorf("error hashing file content, received %v.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error hashing file content.")
+	}
+
+	if _, err := hashFile(tmpfn, 4); err == nil {
+		t.Error("no error hashing file larger than max size.")
+	}
+}
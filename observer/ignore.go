@@ -0,0 +1,327 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ignoreFileNames are the names of ignore files read when using SetGitignore.
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+// ignoreRule is one line of an ignore file.
+type ignoreRule struct {
+	base     string   // Absolute path of the directory the rule is relative to.
+	segments []string // Pattern path segments.
+	negate   bool     // Pattern starts with "!", matching files are not ignored.
+	dirOnly  bool     // Pattern ends with "/", match only directories.
+	anyRoot  bool     // Rule is also relative to the watch patterns roots.
+}
+
+// Ignore adds file patterns to exclude from watching, patterns use the
+// .gitignore syntax relative to the current directory, for example
+// "*.swp" ignores swap files in all directories, "vendor/" ignores the
+// vendor directories and "!keep.swp" does not ignore "keep.swp" files,
+// patterns with no separator match files in any directory under the current
+// directory or under a watch pattern root directory, e.g. "src" for
+// "src/**/*.go", and absolute file paths are relative to the root directory.
+// Patterns are checked in order, the last matching pattern wins.
+func (o *Observer) Ignore(patterns []string) error {
+	o.init()
+
+	base, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+
+	rules := make([]ignoreRule, 0, len(patterns))
+	for _, p := range patterns {
+		path := strings.TrimPrefix(p, "!")
+
+		// Patterns with no separator match files in any directory under
+		// the current directory and the watch patterns roots.
+		if !strings.Contains(strings.TrimRight(filepath.ToSlash(path), "/"), "/") {
+			for _, r := range parseIgnoreRules(base, []string{p}) {
+				r.anyRoot = true
+				rules = append(rules, r)
+			}
+			continue
+		}
+
+		if !filepath.IsAbs(path) {
+			rules = append(rules, parseIgnoreRules(base, []string{p})...)
+			continue
+		}
+
+		// Absolute file system paths are anchored at the root directory.
+		root := filepath.VolumeName(path) + string(filepath.Separator)
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		pattern := "/" + filepath.ToSlash(rel)
+		if strings.HasSuffix(path, string(filepath.Separator)) {
+			pattern += "/"
+		}
+		if strings.HasPrefix(p, "!") {
+			pattern = "!" + pattern
+		}
+
+		rules = append(rules, parseIgnoreRules(root, []string{pattern})...)
+	}
+
+	// Lock:
+	// 1. operations on ignore rules.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.ignoreRules = append(o.ignoreRules, rules...)
+	return nil
+}
+
+// SetGitignore set whether to honor .gitignore and .ignore files found in
+// watched directories, it should be called before Watch.
+func (o *Observer) SetGitignore(enabled bool) {
+	o.init()
+
+	// Lock:
+	// 1. operations on ignore rules.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.gitignore = enabled
+}
+
+// ignored returns a boolean asserting whether a file is excluded from
+// watching.
+func (o *Observer) ignored(path string, isDir bool) bool {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using ignored must be locked
+	// for operations using o.ignoreRules and o.ignoreFiles.
+	if len(o.ignoreRules) == 0 && !o.gitignore {
+		return false
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	// The git directory is always ignored when using ignore files.
+	ignored := false
+	if o.gitignore {
+		root := filepath.VolumeName(abs) + string(filepath.Separator)
+		ignored = matchIgnoreRules(parseIgnoreRules(root, []string{".git/"}), abs, isDir, ignored)
+	}
+
+	// Check ignore files from the top directory down, and then the user
+	// ignore patterns.
+	dirs := make([]string, 0)
+	for dir := range o.ignoreFiles {
+		if isUnder(dir, abs) {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) < len(dirs[j]) })
+
+	for _, dir := range dirs {
+		ignored = matchIgnoreRules(o.ignoreFiles[dir], abs, isDir, ignored)
+	}
+
+	return matchIgnoreRules(o.ignoreRules, abs, isDir, ignored, o.watchRoots()...)
+}
+
+// watchRoots returns the absolute root directories of the watch patterns.
+func (o *Observer) watchRoots() (roots []string) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using watchRoots must be locked
+	// for operations using o.watchPatterns.
+	for _, p := range o.watchPatterns.Values() {
+		root := filepath.Dir(p)
+		if isRecursive(p) {
+			root = recursiveRoot(p)
+		}
+
+		if abs, err := filepath.Abs(root); err == nil {
+			roots = append(roots, abs)
+		}
+	}
+
+	return
+}
+
+// loadIgnoreFiles reads the ignore files in a directory.
+func (o *Observer) loadIgnoreFiles(dir string) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using loadIgnoreFiles must be locked
+	// for operations using o.ignoreFiles.
+	if !o.gitignore {
+		return
+	}
+
+	base, err := filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+
+	rules := make([]ignoreRule, 0)
+	for _, name := range ignoreFileNames {
+		lines, err := readLines(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, parseIgnoreRules(base, lines)...)
+	}
+
+	if o.ignoreFiles == nil {
+		o.ignoreFiles = make(map[string][]ignoreRule)
+	}
+
+	if len(rules) == 0 {
+		delete(o.ignoreFiles, base)
+		return
+	}
+	o.ignoreFiles[base] = rules
+}
+
+// isIgnoreFile returns a boolean asserting whether a file is an ignore file.
+func isIgnoreFile(path string) bool {
+	base := filepath.Base(path)
+
+	for _, name := range ignoreFileNames {
+		if base == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseIgnoreRules parse .gitignore syntax patterns relative to base.
+func parseIgnoreRules(base string, patterns []string) (rules []ignoreRule) {
+	for _, p := range patterns {
+		p = strings.TrimRight(p, " \t\r")
+
+		// Skip empty lines and comments.
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+
+		if strings.HasPrefix(p, "!") {
+			rule.negate = true
+			p = p[1:]
+		}
+
+		// Escaped leading "#" or "!".
+		if strings.HasPrefix(p, "\\#") || strings.HasPrefix(p, "\\!") {
+			p = p[1:]
+		}
+
+		if strings.HasSuffix(p, "/") {
+			rule.dirOnly = true
+			p = strings.TrimRight(p, "/")
+		}
+
+		// Patterns with no inner separator match at any depth.
+		if strings.HasPrefix(p, "/") {
+			p = p[1:]
+		} else if !strings.Contains(p, "/") {
+			p = recursiveToken + "/" + p
+		}
+
+		if p == "" {
+			continue
+		}
+
+		rule.segments = strings.Split(p, "/")
+		rules = append(rules, rule)
+	}
+
+	return
+}
+
+// matchIgnoreRules returns a boolean asserting whether a file is ignored,
+// after checking the rules in order, starting from the ignored state, rules
+// relative to the watch patterns roots are checked using roots.
+func matchIgnoreRules(rules []ignoreRule, path string, isDir bool, ignored bool, roots ...string) bool {
+	for _, r := range rules {
+		match := r.match(r.base, path, isDir)
+		for _, root := range roots {
+			if match || !r.anyRoot {
+				break
+			}
+			match = r.match(root, path, isDir)
+		}
+
+		if match {
+			ignored = !r.negate
+		}
+	}
+
+	return ignored
+}
+
+// match returns a boolean asserting whether the rule, relative to base,
+// matches a file, or one of the file parent directories.
+func (r ignoreRule) match(base string, path string, isDir bool) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." || !isUnder(base, path) {
+		return false
+	}
+
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i <= len(segments); i++ {
+		// All the parents of a file are directories.
+		if r.dirOnly && i == len(segments) && !isDir {
+			continue
+		}
+
+		if matchSegments(r.segments, segments[:i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readLines returns the lines of a text file.
+func readLines(name string) (lines []string, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	err = scanner.Err()
+
+	return
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMatchIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules("/src", []string{
+		"# comment",
+		"*.swp",
+		"!keep.swp",
+		"vendor/",
+		"/build",
+		"doc/*.txt",
+		"gen/**/*.go",
+	})
+
+	matches := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"/src/main.go", false, false},
+		{"/src/a/.main.go.swp", false, true},
+		{"/src/a/keep.swp", false, false},
+		{"/src/vendor", true, true},
+		{"/src/vendor", false, false},
+		{"/src/a/vendor/pkg/main.go", false, true},
+		{"/src/build/main.o", false, true},
+		{"/src/a/build/main.o", false, false},
+		{"/src/doc/readme.txt", false, true},
+		{"/src/a/doc/readme.txt", false, false},
+		{"/src/gen/a/b/main.go", false, true},
+		{"/other/main.swp", false, false},
+	}
+
+	for _, m := range matches {
+		if matchIgnoreRules(rules, m.path, m.isDir, false) != m.ignored {
+			t.Errorf("error matching ignore rules for %s.", m.path)
+		}
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	var o Observer
+
+	names := make(chan string, 100)
+
+	// Create a temporary dir tree with a .gitignore file
+	content := []byte("temporary content")
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	if err := os.MkdirAll(filepath.Join(dir, "vendor", "pkg"), 0777); err != nil {
+		t.Error("error create temp sub dir.")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("vendor/\n"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+
+	o.SetGitignore(true)
+	o.Ignore([]string{"*.swp"})
+	o.Watch([]string{filepath.Join(dir, "**", "*")})
+	defer o.Close()
+
+	o.mutex.Lock()
+	_, ok := o.watchDirs[filepath.Join(dir, "vendor", "pkg")]
+	o.mutex.Unlock()
+	if ok {
+		t.Error("error skipping ignored directory.")
+	}
+
+	o.AddListener(func(e interface{}) {
+		select {
+		case names <- e.(WatchEvent).Name:
+		default:
+		}
+	})
+
+	// Write ignored files, and then a watched file.
+	for _, name := range []string{"main.go.swp", "main.go"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0666); err != nil {
+			t.Error("error writing to temp file.")
+		}
+	}
+	timeout := time.After(5 * time.Second)
+	for received := false; !received; {
+		select {
+		case n := <-names:
+			if filepath.Ext(n) == ".swp" {
+				t.Errorf("error receiving events for ignored file %s.", n)
+			}
+			received = n == filepath.Join(dir, "main.go")
+		case <-timeout:
+			t.Fatal("error waiting for watched file event.")
+		}
+	}
+}
+
+func TestIgnoreAncestor(t *testing.T) {
+	var o Observer
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Fatal("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	project := filepath.Join(dir, "build", "project")
+	if err := os.MkdirAll(project, 0777); err != nil {
+		t.Fatal("error create temp sub dir.")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal("error getting current directory.")
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(project); err != nil {
+		t.Fatal("error changing to temp sub dir.")
+	}
+
+	o.Ignore([]string{"build"})
+	o.Watch([]string{filepath.Join(project, "**", "*.go")})
+	defer o.Close()
+
+	// Directories above the current directory and the watch pattern root
+	// do not match patterns.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.ignored(filepath.Join("src", "a.go"), false) {
+		t.Error("error matching ignore pattern against a parent of the current directory.")
+	}
+	if !o.ignored(filepath.Join("src", "build", "a.go"), false) {
+		t.Error("error matching ignore pattern under the current directory.")
+	}
+}
+
+func TestIgnoreUnwatch(t *testing.T) {
+	var o Observer
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Fatal("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	vendor := filepath.Join(dir, "vendor")
+	if err := os.MkdirAll(vendor, 0777); err != nil {
+		t.Fatal("error create temp sub dir.")
+	}
+
+	// The ignored directory is watched only by the non recursive pattern.
+	o.Ignore([]string{vendor + string(filepath.Separator)})
+	o.Watch([]string{filepath.Join(dir, "**", "*.go")})
+	o.Watch([]string{filepath.Join(vendor, "*.go")})
+	defer o.Close()
+
+	o.Unwatch([]string{filepath.Join(dir, "**", "*.go")})
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.watchDirs[vendor] != 1 {
+		t.Errorf("error releasing a directory not used by the unwatched pattern, refs %d.", o.watchDirs[vendor])
+	}
+	if _, ok := o.watchDirs[dir]; ok {
+		t.Error("error releasing the directories of the unwatched pattern.")
+	}
+}
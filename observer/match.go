@@ -0,0 +1,117 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// recursiveToken is the path segment matching zero or more directories.
+const recursiveToken = "**"
+
+// splitPath splits a path into its separator delimited segments.
+func splitPath(p string) []string {
+	return strings.Split(p, string(filepath.Separator))
+}
+
+// isRecursive returns a boolean asserting whether a pattern use the
+// recursive "**" segment.
+func isRecursive(pattern string) bool {
+	for _, s := range splitPath(pattern) {
+		if s == recursiveToken {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recursiveRoot returns the directory part of a recursive pattern
+// preceding the first "**" segment.
+//
+// For example if pattern is 'src/**/*.go', root will be 'src'.
+func recursiveRoot(pattern string) string {
+	segments := splitPath(pattern)
+
+	for i, s := range segments {
+		if s == recursiveToken {
+			// Pattern starting with "**" is relative to current directory,
+			// pattern starting with "/**" is relative to the file system root.
+			if i == 0 {
+				return "."
+			}
+			if i == 1 && segments[0] == "" {
+				return string(filepath.Separator)
+			}
+
+			return strings.Join(segments[:i], string(filepath.Separator))
+		}
+	}
+
+	return filepath.Dir(pattern)
+}
+
+// matchRecursive reports whether name matches a pattern that may include
+// "**" segments, "**" matches zero or more directories.
+//
+// Both pattern and name are cleaned before matching, because directories
+// found while walking a tree do not keep the './' prefix.
+func matchRecursive(pattern string, name string) bool {
+	return matchSegments(
+		splitPath(filepath.Clean(pattern)),
+		splitPath(filepath.Clean(name)))
+}
+
+// matchSegments matches path segments against pattern segments.
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		// Try to match the rest of the pattern at every depth.
+		if pattern[0] == recursiveToken {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if match, _ := filepath.Match(pattern[0], name[0]); !match {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// isUnder returns a boolean asserting whether path is dir or inside dir.
+func isUnder(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"testing"
+)
+
+func TestMatchRecursive(t *testing.T) {
+	matches := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"./**/*.go", "a/main.go", true},
+		{"./**/*.go", "./main.go", true},
+		{"src/**/*.go", "src/a/main.c", false},
+		{"src/**/*.go", "pkg/a/main.go", false},
+		{"src/**/test/*.go", "src/a/test/main.go", true},
+		{"src/**/test/*.go", "src/a/main.go", false},
+		{"src/**", "src/a/main.go", true},
+	}
+
+	for _, m := range matches {
+		if matchRecursive(m.pattern, m.name) != m.match {
+			t.Errorf("error matching %s with %s.", m.name, m.pattern)
+		}
+	}
+}
+
+func TestRecursiveRoot(t *testing.T) {
+	roots := map[string]string{
+		"src/**/*.go":     "src",
+		"./**/*.go":       ".",
+		"**/*.go":         ".",
+		"/src/a/**/*.go":  "/src/a",
+		"src/a/**/b/**/*": "src/a",
+	}
+
+	for pattern, root := range roots {
+		if recursiveRoot(pattern) != root {
+			t.Errorf("error getting root of %s.", pattern)
+		}
+	}
+}
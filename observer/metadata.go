@@ -0,0 +1,66 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"os"
+	"time"
+)
+
+// FileInfo is the file metadata captured when a file event is sent.
+type FileInfo struct {
+	Size    int64       // File size in bytes.
+	Mode    os.FileMode // File mode bits.
+	ModTime time.Time   // File modification time.
+	IsDir   bool        // File is a directory.
+	Link    string      // Symbolic link target, empty if file is not a link.
+}
+
+// SetMetadata set whether file events carry the file metadata, when set
+// the event Info field holds the file size, mode, modification time and
+// symbolic link target, files that no longer exist have nil Info.
+func (o *Observer) SetMetadata(enabled bool) {
+	o.init()
+
+	// Lock:
+	// 1. operations on the metadata flag.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.metadata = enabled
+}
+
+// fileInfo returns the metadata of a file, or nil if the file does not exist.
+func fileInfo(name string) *FileInfo {
+	info, err := os.Lstat(name)
+	if err != nil {
+		return nil
+	}
+
+	fi := &FileInfo{
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		fi.Link, _ = os.Readlink(name)
+	}
+
+	return fi
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetMetadata(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_metadata.txt")
+	link := filepath.Join(dir, "test_metadata.link")
+
+	// watch temporary files using a polling backend
+	b := NewPollingBackend(0)
+	o.SetBackend(b)
+	o.SetMetadata(true)
+	o.Watch([]string{filepath.Join(dir, "*.txt"), link})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	if err := ioutil.WriteFile(tmpfn, []byte("content"), 0600); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	b.Poll()
+
+	select {
+	case e := <-events:
+		if e.Pattern != filepath.Join(dir, "*.txt") {
+			t.Errorf("error setting event pattern, received %v.", e.Pattern)
+		}
+		if e.Info == nil || e.Info.Size != 7 || e.Info.Mode.Perm() != 0600 || e.Info.IsDir {
+			t.Errorf("error setting event metadata, received %v.", e.Info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error receiving file event.")
+	}
+
+	if err := os.Symlink(tmpfn, link); err != nil {
+		t.Skip("symbolic links not supported.")
+	}
+	b.Poll()
+
+	select {
+	case e := <-events:
+		if e.Pattern != link || e.Info == nil || e.Info.Link != tmpfn {
+			t.Errorf("error setting symbolic link metadata, received %v %v.", e.Pattern, e.Info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error receiving symbolic link event.")
+	}
+}
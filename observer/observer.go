@@ -17,112 +17,221 @@
 package observer
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"path/filepath"
 	"sync"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
-
 	"github.com/yaacov/observer/observer/set"
 )
 
 // Listener is the function type to run on events.
 type Listener func(interface{})
 
+// ContextListener is the function type to run on events, the context is
+// cancelled when the observer is closed.
+type ContextListener func(context.Context, interface{})
+
 // Observer emplements the observer pattern.
 type Observer struct {
-	quit           chan bool
-	events         chan interface{}
-	watcher        *fsnotify.Watcher
+	ctx            context.Context
+	cancel         context.CancelFunc
+	events         chan message
+	queueSize      int
+	backend        Backend
 	watchPatterns  set.Set
-	watchDirs      set.Set
-	listeners      []Listener
+	watchDirs      map[string]int
+	patternRefs    map[string][]string
+	patternOps     map[string]Op
+	ignoreRules    []ignoreRule
+	ignoreFiles    map[string][]ignoreRule
+	gitignore      bool
+	listeners      []listener
+	lastID         uint64
 	mutex          *sync.Mutex
-	bufferEvents   []interface{}
+	buffers        map[string]*debounce
 	bufferDuration time.Duration
+	bufferKey      KeyFunc
+	debounceMode   DebounceMode
+	maxWait        time.Duration
+	clock          Clock
+	state          State
+	bufferFlushed  bool
+	renameWindow   time.Duration
+	renames        []*pendingRename
+	saveQuiet      time.Duration
+	saves          map[string]*pendingSave
+	saveFiles      map[string]bool
+	hashMax        int64
+	hashes         map[string]string
+	stableQuiet    time.Duration
+	stable         map[string]*pendingStable
+	metadata       bool
+	onError        ErrorHook
+	deliveryMode   DeliveryMode
+	queues         map[uint64]*queue
+	pool           *pool
+	outbox         []job
+	dispatched     uint64
+	remaining      map[uint64]int
+	expiry         map[uint64]Timer
+	running        map[uint64]int
+	runningCount   int
+	drained        chan struct{}
+	loops          sync.WaitGroup
 	Verbose        bool
 }
 
 // Open the observer channles and run the event loop,
 // it will return an error if event loop already running.
 func (o *Observer) Open() error {
-	// Check for mutex
-	if o.mutex == nil {
-		o.mutex = &sync.Mutex{}
-	}
+	return o.OpenContext(context.Background())
+}
+
+// OpenContext open the observer channles and run the event loop, when ctx is
+// cancelled the observer is closed, it will return an error if event loop
+// already running.
+func (o *Observer) OpenContext(ctx context.Context) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on the events channel.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 
-	if o.events != nil {
+	if o.state != StateNew {
 		return fmt.Errorf("Observer already inititated.")
 	}
 
 	// Create the observer channels.
-	o.quit = make(chan bool)
-	o.events = make(chan interface{})
+	size := o.queueSize
+	if size == 0 {
+		size = DefaultQueueSize
+	}
+	o.events = make(chan message, size)
+	o.state = StateOpen
+
+	// Close the observer when ctx is cancelled.
+	go func() {
+		select {
+		case <-ctx.Done():
+			o.Close()
+		case <-o.ctx.Done():
+		}
+	}()
 
 	// Run the observer.
 	return o.eventLoop()
 }
 
-// Close the observer channles,
-// it will return an error if close fails.
-func (o *Observer) Close() error {
-	// Close event loop
-	if o.events != nil {
-		// Send a quit signal.
-		o.quit <- true
+// Close the observer channles, and wait for the event loop and the watch
+// loop to exit, it will return an error if close fails.
+func (o *Observer) Close() (err error) {
+	o.init()
+
+	// Stop the event loop, the watch loop and cancel the listeners context.
+	o.cancel()
 
-		// Close channels.
-		close(o.quit)
-		close(o.events)
+	// Drop buffered events.
+	o.mutex.Lock()
+	o.state = StateClosed
+	o.stopBuffer()
+	o.stopRenames()
+	o.stopSaves()
+	o.stopStable()
+	backend := o.backend
+	pool := o.pool
+	o.mutex.Unlock()
+
+	// Stop the dispatch pool workers.
+	if pool != nil {
+		pool.close(o)
 	}
 
 	// Close file watcher.
-	if o.watcher != nil {
-		o.watcher.Close()
+	if backend != nil {
+		err = backend.Close()
 	}
 
-	return nil
+	// Wait for the event loop and the watch loop to exit.
+	o.loops.Wait()
+
+	return
 }
 
 // AddListener adds a listener function to run on event,
-// the listener function will recive the event object as argument.
-func (o *Observer) AddListener(l Listener) {
-	// Check for mutex
-	if o.mutex == nil {
-		o.mutex = &sync.Mutex{}
-	}
+// the listener function will recive the event object as argument,
+// the returned subscription can be used to remove the listener.
+func (o *Observer) AddListener(l Listener) Subscription {
+	return o.addListener(AllTopics, l)
+}
 
-	// Lock:
-	// 1. operations on array listeners
-	o.mutex.Lock()
-	defer o.mutex.Unlock()
+// AddContextListener adds a listener function to run on event, the listener
+// function will recive a context cancelled when the observer is closed, and
+// the event object as arguments.
+func (o *Observer) AddContextListener(l ContextListener) Subscription {
+	return o.addContextListener(AllTopics, l)
+}
+
+// addListener adds a listener function to run on events emitted on
+// matching topics.
+func (o *Observer) addListener(topic string, l Listener) Subscription {
+	return o.addContextListener(topic, func(ctx context.Context, e interface{}) {
+		l(e)
+	})
+}
+
+// addContextListener adds a context listener function to run on events
+// emitted on matching topics.
+func (o *Observer) addContextListener(topic string, l ContextListener) Subscription {
+	return o.addErrorListener(topic, func(ctx context.Context, e interface{}) error {
+		l(ctx, e)
+		return nil
+	})
+}
 
-	o.listeners = append(o.listeners, l)
+// addErrorListener adds a listener function returning an error to run on
+// events emitted on matching topics.
+func (o *Observer) addErrorListener(topic string, l listenerFunc) Subscription {
+	return o.addLimitedListener(listener{topic: topic, fn: l}, 0, 0)
 }
 
 // Emit an event, and event can be of any type, when event is triggered all
-// listeners will be called using the event object.
-func (o *Observer) Emit(event interface{}) {
-	o.events <- event
+// listeners will be called using the event object, it will return
+// ErrNotOpen if the observer is not opened and ErrClosed if the observer
+// is closed.
+func (o *Observer) Emit(event interface{}) error {
+	return o.EmitTopic("", event)
 }
 
 // Watch for file changes, watching a file can be done using exact file name,
-// or shell pattern matching.
+// or shell pattern matching, a "**" path segment matches zero or more
+// directories, e.g. 'src/**/*.go' will watch all go files under 'src'.
+// Watch emit events for the DefaultOps file operations.
 func (o *Observer) Watch(files []string) error {
-	// Check for mutex
-	if o.mutex == nil {
-		o.mutex = &sync.Mutex{}
-	}
+	return o.WatchOps(files, DefaultOps)
+}
+
+// WatchOps for file changes, same as Watch, emit events only for the ops
+// file operations, e.g. WatchOps(files, Create|Rename) emit only events
+// for created and renamed files.
+func (o *Observer) WatchOps(files []string, ops Op) error {
+	o.init()
 
 	// Lock:
 	// 1. operations on watchPatterns set.
+	// 2. operations on watchDirs map.
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
 	// Init watcher on first call.
-	if o.watcher == nil {
+	if o.watchDirs == nil {
+		o.watchDirs = make(map[string]int)
+		o.patternRefs = make(map[string][]string)
+		o.patternOps = make(map[string]Op)
+
 		err := o.watchLoop()
 		if err != nil {
 			return err
@@ -131,26 +240,35 @@ func (o *Observer) Watch(files []string) error {
 
 	// Add file patterns and dirs to watch list.
 	for _, f := range files {
-		// For example if file is '/home/.config/*.conf':
-		// base will be '*.conf'
-		// dir will be '/home/.config'
-		base := filepath.Base(f)
-		dir := filepath.Dir(f)
-
-		// Pattern calculation does not allways equal f from user.
-		// We can not use the user provided file name here, because
-		// in cases where we have no directory with the file name, we
-		// do want to add the current directory './' before the base file
-		// name. We can not use filepath.Join for the same reason, it will
-		// remove the './' prefix when cleaning filename.
-		pattern := fmt.Sprintf("%s%s%s", dir, string(filepath.Separator), base)
+		pattern := watchPattern(f)
+
+		// Each pattern holds one reference on each of it's directories.
+		if o.watchPatterns.Has(pattern) {
+			o.patternOps[pattern] |= ops
+			continue
+		}
+
+		// The pattern root is used by ignore rules while walking.
+		o.watchPatterns.Add(pattern)
+		dirs, err := o.patternDirs(pattern)
+		if err != nil {
+			o.watchPatterns.Remove(pattern)
+			return err
+		}
 
 		// Logging file patterns.
 		if o.Verbose {
 			log.Printf("[Debug] Adding pattern: %s", pattern)
 		}
-		o.watchPatterns.Add(pattern)
-		o.watchDirs.Add(dir)
+		o.patternOps[pattern] = ops
+		o.patternRefs[pattern] = dirs
+
+		for _, d := range dirs {
+			o.watchDirs[d]++
+			o.loadIgnoreFiles(d)
+			o.hashDir(d)
+			o.saveDir(d)
+		}
 	}
 
 	// NOTE: We watch directories and not files.
@@ -161,8 +279,8 @@ func (o *Observer) Watch(files []string) error {
 	// When a files is watched by name ane deleted, fsnotify will stop send
 	// notifications for this file, watching a directory we will pick up
 	// the new file with the same name and continue to get notifications.
-	for _, d := range o.watchDirs.Values() {
-		err := o.watcher.Add(d)
+	for d := range o.watchDirs {
+		err := o.backend.Add(d)
 		if err != nil {
 			return err
 		}
@@ -176,73 +294,179 @@ func (o *Observer) Watch(files []string) error {
 	return nil
 }
 
+// Unwatch stop watching file patterns added using Watch, a directory is
+// watched until the last pattern using it is removed, patterns that are not
+// watched are ignored.
+func (o *Observer) Unwatch(files []string) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on watchPatterns set.
+	// 2. operations on watchDirs map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// Nothing is watched yet.
+	if o.watchDirs == nil {
+		return nil
+	}
+
+	for _, f := range files {
+		pattern := watchPattern(f)
+
+		if !o.watchPatterns.Has(pattern) {
+			continue
+		}
+
+		// Get the directories referenced by this pattern.
+		dirs := o.patternRefs[pattern]
+
+		// Logging file patterns.
+		if o.Verbose {
+			log.Printf("[Debug] Removing pattern: %s", pattern)
+		}
+		o.watchPatterns.Remove(pattern)
+		delete(o.patternOps, pattern)
+		delete(o.patternRefs, pattern)
+
+		for _, d := range dirs {
+			o.releaseDir(d)
+		}
+	}
+
+	return nil
+}
+
+// SetBackend set the file watcher backend, the default backend is using
+// fsnotify, it will return an error if the observer is already watching files.
+func (o *Observer) SetBackend(b Backend) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on the file watcher.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.watchDirs != nil {
+		return fmt.Errorf("Watcher already inititated.")
+	}
+
+	o.backend = b
+	return nil
+}
+
 // SetBufferDuration set the event buffer damping duration.
 func (o *Observer) SetBufferDuration(d time.Duration) {
 	// Set the buffer duration.
 	o.bufferDuration = d
 }
 
-// sendEvent send one or more events to the observer listeners.
-func (o *Observer) sendEvent(event interface{}) {
+// sendEvent send an event to the observer listeners subscribed to the
+// event topic.
+func (o *Observer) sendEvent(m message) {
 	// NOTE: we do not lock this function directly.
 	//
 	// All functions using sendEvent must be locked
 	// for operations using o.listeners.
-	for _, listener := range o.listeners {
-		go listener(event)
+	for _, l := range o.listeners {
+		if matchTopic(l.topic, m.topic) && l.accepts(m.event) {
+			o.dispatch(l, m.event)
+		}
+	}
+}
+
+// sendEvents send buffered events to the observer listeners, each listener
+// will recive the events emitted on topics it is subscribed to.
+func (o *Observer) sendEvents(messages []message) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using sendEvents must be locked
+	// for operations using o.listeners.
+	for _, l := range o.listeners {
+		events := make([]interface{}, 0, len(messages))
+		for _, m := range messages {
+			if matchTopic(l.topic, m.topic) && l.accepts(m.event) {
+				events = append(events, m.event)
+			}
+		}
+
+		if len(events) > 0 {
+			o.dispatch(l, events)
+		}
 	}
 }
 
 // handleEvent handle an event.
-func (o *Observer) handleEvent(event interface{}, f *string) {
+func (o *Observer) handleEvent(m message, e *WatchEvent) {
 	// Lock:
 	// 1. operations on listeners array (sendEvent).
-	// 2. operations on bufferEvents array.
+	// 2. operations on buffers map.
 	// 3. operations using the watchPatterns set (matchFile).
 	o.mutex.Lock()
-	defer o.mutex.Unlock()
+	defer o.unlock()
 
-	// Check for file name match, nil is match all.
-	if !o.matchFile(f) {
+	// Events received after the observer is closed are dropped.
+	if o.state == StateClosed {
 		return
 	}
 
-	// If we do not buffer events, just send this event now.
-	if o.bufferDuration == 0 {
-		o.sendEvent(event)
+	// Check for file name and operation match, nil is match all.
+	if !o.matchFile(e) {
 		return
 	}
 
-	// Add new event to the event buffer.
-	o.bufferEvents = append(o.bufferEvents, event)
+	// Send the event with the matching watch pattern.
+	if e != nil {
+		m.event = *e
+	}
 
-	// If this is the first event, set a timeout function.
-	if len(o.bufferEvents) == 1 {
-		time.AfterFunc(o.bufferDuration, func() {
-			// Lock:
-			// 1. operations on listeners array (sendEvent).
-			// 2. operations on bufferEvents array.
-			o.mutex.Lock()
-			defer o.mutex.Unlock()
+	// If we do not buffer events, or the buffer was already flushed by
+	// Shutdown, just send this event now.
+	if o.bufferDuration == 0 || o.bufferFlushed {
+		o.sendEvent(m)
+		return
+	}
+
+	// Add new event to the event buffer of the event key.
+	o.bufferEvent(m)
+}
 
-			// Send all events in event buffer.
-			o.sendEvent(o.bufferEvents)
+// handleWatchEvent handle a file watcher event.
+func (o *Observer) handleWatchEvent(e WatchEvent) {
+	// Drop writes that did not change the file content.
+	if !o.hashContent(&e) {
+		return
+	}
 
-			// Reset events buffer.
-			o.bufferEvents = make([]interface{}, 0)
-		})
+	// Add the file metadata.
+	o.mutex.Lock()
+	metadata := o.metadata
+	o.mutex.Unlock()
+	if metadata {
+		e.Info = fileInfo(e.Name)
 	}
+
+	o.handleEvent(message{topic: watchTopic(e.Op), event: e}, &e)
 }
 
 // eventLoop runs the event loop.
 func (o *Observer) eventLoop() error {
 	// Run observer.
+	o.loops.Add(1)
 	go func() {
+		defer o.loops.Done()
+
 		for {
 			select {
-			case event := <-o.events:
-				o.handleEvent(event, nil)
-			case <-o.quit:
+			case m := <-o.events:
+				// Signal that all previous events were handled.
+				if m.barrier != nil {
+					close(m.barrier)
+					continue
+				}
+
+				o.handleEvent(m, nil)
+			case <-o.ctx.Done():
 				return
 			}
 		}
@@ -251,69 +475,169 @@ func (o *Observer) eventLoop() error {
 	return nil
 }
 
-// matchFile returns a boolean asserting whether this file is watched or not.
-func (o Observer) matchFile(f *string) (match bool) {
+// init the observer mutex and context.
+func (o *Observer) init() {
+	// Check for mutex
+	if o.mutex == nil {
+		o.mutex = &sync.Mutex{}
+	}
+
+	// Check for context
+	if o.ctx == nil {
+		o.ctx, o.cancel = context.WithCancel(context.Background())
+	}
+
+	// Check for clock
+	if o.clock == nil {
+		o.clock = realClock{}
+	}
+}
+
+// matchFile returns a boolean asserting whether this file event is watched
+// or not, and set the event matching watch pattern.
+func (o *Observer) matchFile(e *WatchEvent) bool {
 	// If no file, return true.
-	if f == nil {
-		match = true
+	if e == nil {
+		return true
+	}
 
-		return
+	// Renamed files match using the new or the old file name.
+	e.Pattern = o.matchPattern(e.Name, e.Op)
+	if e.Pattern == "" && e.OldName != "" {
+		e.Pattern = o.matchPattern(e.OldName, e.Op)
+	}
+
+	return e.Pattern != ""
+}
+
+// matchName returns a boolean asserting whether this file name and
+// operation are watched or not.
+func (o *Observer) matchName(f string, op Op) bool {
+	return o.matchPattern(f, op) != ""
+}
+
+// matchPattern returns the watch pattern matching this file name and
+// operation, or an empty string if the file is not watched, an exact file
+// name pattern is returned first, o/w the first added pattern matching.
+func (o *Observer) matchPattern(f string, op Op) string {
+	// Check for ignored files, known directories are directories.
+	if _, isDir := o.watchDirs[filepath.Clean(f)]; o.ignored(f, isDir) {
+		return ""
 	}
 
 	// Look for an exact match.
-	match = o.watchPatterns.Has(*f)
-	if match {
-		return
+	if o.watchPatterns.Has(f) && o.patternOps[f]&op != 0 {
+		return f
 	}
 
 	// Try to match shell file name pattern.
 	for _, p := range o.watchPatterns.Values() {
-		match, _ = filepath.Match(p, *f)
+		// Check for a watched file operation.
+		if o.patternOps[p]&op == 0 {
+			continue
+		}
+
+		var match bool
+		if isRecursive(p) {
+			match = matchRecursive(p, f)
+		} else {
+			match, _ = filepath.Match(p, f)
+		}
 		if match {
-			return
+			return p
 		}
 	}
 
-	return
+	return ""
 }
 
 // watchLoop runs a watcher loop for file changes.
 func (o *Observer) watchLoop() error {
-	var err error
+	// Use the default backend.
+	if o.backend == nil {
+		b, err := NewFSNotifyBackend()
+		if err != nil {
+			return err
+		}
 
-	o.watcher, err = fsnotify.NewWatcher()
-	if err != nil {
-		return err
+		o.backend = b
 	}
 
 	// Listen for file/directory changes.
+	events := o.backend.Events()
+	errors := o.backend.Errors()
+
+	o.loops.Add(1)
 	go func() {
+		defer o.loops.Done()
+
 		for {
 			select {
-			case event := <-o.watcher.Events:
+			case e, ok := <-events:
+				// Watcher closed.
+				if !ok {
+					return
+				}
+
 				// Logging all events.
 				if o.Verbose {
-					log.Printf("[Debug] Received event: %v", event)
+					log.Printf("[Debug] Received event: %v", e)
 				}
 
-				// Convert fsnotify Event into observer Event
-				e := WatchEvent{
-					Name: event.Name,
-					Op:   Op(event.Op),
+				o.handleBackendEvent(e)
+				o.handled()
+			case err, ok := <-errors:
+				// Watcher closed.
+				if !ok {
+					return
 				}
 
-				// Check if event is write create or delete event
-				if e.Op&Write == Write || e.Op&Create == Create || e.Op&Remove == Remove {
-					// Check for event filename pattern match.
-					o.handleEvent(e, &e.Name)
-				}
-			case err := <-o.watcher.Errors:
 				if err != nil {
-					o.handleEvent(err, nil)
+					o.handleEvent(message{topic: WatchTopic + ".error", event: err}, nil)
 				}
+				o.handled()
+			case <-o.ctx.Done():
+				return
 			}
 		}
 	}()
 
 	return nil
 }
+
+// handleBackendEvent handle an event received from the file watcher backend.
+func (o *Observer) handleBackendEvent(e WatchEvent) {
+	// Track created and removed directories, and check for
+	// files created inside new directories.
+	for _, created := range o.handleDirEvent(e) {
+		o.handleWatchEvent(created)
+	}
+
+	// Merge the old and new names of renamed files.
+	e, send := o.trackRename(e)
+	if !send {
+		return
+	}
+
+	// Coalesce atomic saves into one logical change.
+	if o.coalesceSave(e) {
+		return
+	}
+
+	// Wait for written files to be stable.
+	e, send = o.holdUnstable(e)
+	if !send {
+		return
+	}
+
+	// Check for event filename pattern and operation match.
+	o.handleWatchEvent(e)
+}
+
+// handled notify backends implementing Handled that an event or an error
+// was handled.
+func (o *Observer) handled() {
+	if b, ok := o.backend.(interface{ Handled() }); ok {
+		b.Handled()
+	}
+}
@@ -17,9 +17,11 @@
 package observer
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -55,6 +57,107 @@ func TestClose(t *testing.T) {
 	}
 }
 
+func TestOpenContext(t *testing.T) {
+	var o Observer
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := o.OpenContext(ctx)
+	if err != nil {
+		t.Error("error Open a new Observer with context.")
+	}
+
+	cancel()
+
+	// Wait for the observer to close.
+	select {
+	case <-o.ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("error closing Observer on context cancel.")
+	}
+
+	// Emitting on a closed observer should not block.
+	if err := o.Emit("done"); err != ErrClosed {
+		t.Error("error Emitting on a closed Observer.")
+	}
+}
+
+func TestAddContextListener(t *testing.T) {
+	var o Observer
+
+	o.Open()
+
+	started := make(chan bool)
+	done := make(chan bool)
+
+	o.AddContextListener(func(ctx context.Context, e interface{}) {
+		started <- true
+
+		// Wait for the observer to close.
+		<-ctx.Done()
+		done <- true
+	})
+
+	o.Emit("done")
+	<-started
+
+	o.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("error cancelling listener context on Close.")
+	}
+}
+
+func TestCloseGoroutines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_close.txt")
+
+	// Watch only observer.
+	before := runtime.NumGoroutine()
+
+	var o Observer
+	o.Watch([]string{tmpfn})
+	if err := o.Close(); err != nil {
+		t.Errorf("error closing a watching Observer: %v.", err)
+	}
+	checkGoroutines(t, before)
+
+	// Open and watch observer.
+	before = runtime.NumGoroutine()
+
+	var p Observer
+	p.Open()
+	p.Watch([]string{tmpfn})
+	if err := p.Close(); err != nil {
+		t.Errorf("error closing an opened and watching Observer: %v.", err)
+	}
+	checkGoroutines(t, before)
+}
+
+// checkGoroutines fails if the number of running goroutines does not drop
+// to the expected number after a timeout.
+func checkGoroutines(t *testing.T, expected int) {
+	timeout := time.After(5 * time.Second)
+
+	for runtime.NumGoroutine() > expected {
+		select {
+		case <-timeout:
+			buf := make([]byte, 1<<16)
+			buf = buf[:runtime.Stack(buf, true)]
+			t.Errorf("error leaking goroutines, %d running, expected %d:\n%s",
+				runtime.NumGoroutine(), expected, buf)
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
 func TestAddListener(t *testing.T) {
 	var output string
 	var o Observer
@@ -79,6 +182,52 @@ func TestAddListener(t *testing.T) {
 	}
 }
 
+func TestRemoveListener(t *testing.T) {
+	var o Observer
+
+	o.Open()
+	defer o.Close()
+
+	done := make(chan string, 10)
+
+	// A listener that removes it self on first event.
+	var sub Subscription
+	sub = o.AddListener(func(e interface{}) {
+		if err := sub.Unsubscribe(); err == nil {
+			done <- "once"
+		}
+	})
+	o.AddListener(func(e interface{}) {
+		done <- e.(string)
+	})
+
+	o.Emit("first")
+	o.Emit("second")
+
+	received := map[string]int{}
+	for i := 0; i < 3; i++ {
+		received[<-done]++
+	}
+
+	if received["once"] != 1 || received["first"] != 1 || received["second"] != 1 {
+		t.Error("error removing a listener from inside a listener.")
+	}
+
+	if err := o.RemoveListener(sub); err == nil {
+		t.Error("no error removing a removed listener.")
+	}
+
+	// Subscriptions of other observers are not removed.
+	var other Observer
+	foreign := other.AddListener(func(e interface{}) {})
+	if err := o.RemoveListener(foreign); err == nil {
+		t.Error("no error removing a listener of another observer.")
+	}
+	if err := foreign.Unsubscribe(); err != nil {
+		t.Error("error removing a listener using it's own observer.")
+	}
+}
+
 func TestEmit(t *testing.T) {
 	var output string
 	var o Observer
@@ -182,3 +331,183 @@ func TestSetBufferDuration(t *testing.T) {
 		t.Error("error sending 4 buffered events.")
 	}
 }
+
+func TestWatchOps(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 100)
+
+	// Create a temporary dir and files
+	content := []byte("temporary content")
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_watch_ops.txt")
+	if err := ioutil.WriteFile(tmpfn, content, 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+
+	// watch temporary file for mode changes
+	o.WatchOps([]string{tmpfn}, Chmod)
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	// Write to file, and then change it's mode.
+	if err := ioutil.WriteFile(tmpfn, content, 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	if err := os.Chmod(tmpfn, 0600); err != nil {
+		t.Error("error changing temp file mode.")
+	}
+
+	select {
+	case e := <-events:
+		if e.Name != tmpfn || e.Op != Chmod {
+			t.Errorf("error watching file operations, received %v.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error watching file mode changes.")
+	}
+}
+
+func TestWatchRecursive(t *testing.T) {
+	var o Observer
+
+	names := make(chan string, 100)
+
+	// Create a temporary dir tree
+	content := []byte("temporary content")
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0777); err != nil {
+		t.Error("error create temp sub dir.")
+	}
+
+	// watch temporary dir tree
+	err = o.Watch([]string{filepath.Join(dir, "**", "*.txt")})
+	if err != nil {
+		t.Error("error watching recursive pattern.")
+	}
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		select {
+		case names <- e.(WatchEvent).Name:
+		default:
+		}
+	})
+
+	// Write to a file in an existing sub directory.
+	tmpfn := filepath.Join(sub, "test_watch.txt")
+	if err := ioutil.WriteFile(tmpfn, content, 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	waitForName(t, names, tmpfn)
+
+	// Write to a file in a new sub directory.
+	newSub := filepath.Join(dir, "c", "d")
+	if err := os.MkdirAll(newSub, 0777); err != nil {
+		t.Error("error create temp sub dir.")
+	}
+	tmpfn = filepath.Join(newSub, "test_watch.txt")
+	if err := ioutil.WriteFile(tmpfn, content, 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	waitForName(t, names, tmpfn)
+
+	// Remove the new sub directory.
+	if err := os.RemoveAll(filepath.Join(dir, "c")); err != nil {
+		t.Error("error removing temp sub dir.")
+	}
+	waitForName(t, names, tmpfn)
+
+	time.Sleep(100 * time.Millisecond)
+	o.mutex.Lock()
+	_, ok := o.watchDirs[newSub]
+	o.mutex.Unlock()
+	if ok {
+		t.Error("error removing watch for removed directory.")
+	}
+}
+
+func TestUnwatch(t *testing.T) {
+	var o Observer
+
+	names := make(chan string, 100)
+
+	// Create a temporary dir and files
+	content := []byte("temporary content")
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	txtfn := filepath.Join(dir, "test_unwatch.txt")
+	conffn := filepath.Join(dir, "test_unwatch.conf")
+
+	// watch temporary dir using two patterns
+	o.Watch([]string{txtfn, filepath.Join(dir, "*.conf")})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		select {
+		case names <- e.(WatchEvent).Name:
+		default:
+		}
+	})
+
+	// Stop watching one pattern, the directory is still used.
+	err = o.Unwatch([]string{filepath.Join(dir, "*.conf")})
+	if err != nil || o.watchDirs[dir] != 1 {
+		t.Error("error unwatching a pattern in a shared directory.")
+	}
+
+	// Write to both files, only the watched file is received.
+	if err := ioutil.WriteFile(conffn, content, 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	if err := ioutil.WriteFile(txtfn, content, 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+	waitForName(t, names, txtfn)
+
+	select {
+	case n := <-names:
+		if n == conffn {
+			t.Error("error receiving events for unwatched pattern.")
+		}
+	default:
+	}
+
+	// Stop watching the last pattern, the directory is removed.
+	err = o.Unwatch([]string{txtfn})
+	if _, ok := o.watchDirs[dir]; err != nil || ok {
+		t.Error("error unwatching the last pattern of a directory.")
+	}
+}
+
+// waitForName blocks until name is received, or fails after a timeout.
+func waitForName(t *testing.T, names chan string, name string) {
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case n := <-names:
+			if filepath.Clean(n) == name {
+				return
+			}
+		case <-timeout:
+			t.Errorf("error waiting for %s event.", name)
+			return
+		}
+	}
+}
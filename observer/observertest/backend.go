@@ -0,0 +1,221 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observertest implements a fake file watcher backend and a virtual
+// clock for testing code using the observer package.
+package observertest
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/yaacov/observer/observer"
+)
+
+// FakeBackend is an in memory observer.Backend, tests drive the backend by
+// calling Create, Write, RemoveFile, Rename, Move, Chmod and CloseWrite,
+// events are sent only for files in watched directories, same as a real
+// file watcher.
+type FakeBackend struct {
+	dirs    map[string]bool
+	events  chan observer.WatchEvent
+	errors  chan error
+	handled chan struct{}
+	done    chan struct{}
+	mutex   sync.Mutex
+	send    sync.Mutex
+	once    sync.Once
+	cookie  uint32
+}
+
+// NewFakeBackend returns a new fake file watcher backend.
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		dirs:    make(map[string]bool),
+		events:  make(chan observer.WatchEvent),
+		errors:  make(chan error),
+		handled: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Add starts watching a directory.
+func (b *FakeBackend) Add(dir string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.dirs[filepath.Clean(dir)] = true
+	return nil
+}
+
+// Remove stops watching a directory.
+func (b *FakeBackend) Remove(dir string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.dirs[filepath.Clean(dir)] {
+		return fmt.Errorf("Directory not watched.")
+	}
+
+	delete(b.dirs, filepath.Clean(dir))
+	return nil
+}
+
+// Watched returns a boolean asserting whether a directory is watched.
+func (b *FakeBackend) Watched(dir string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.dirs[filepath.Clean(dir)]
+}
+
+// Events returns the file events channel.
+func (b *FakeBackend) Events() <-chan observer.WatchEvent {
+	return b.events
+}
+
+// Errors returns the errors channel.
+func (b *FakeBackend) Errors() <-chan error {
+	return b.errors
+}
+
+// Close removes all watches and closes the events and errors channels.
+func (b *FakeBackend) Close() error {
+	b.once.Do(func() {
+		close(b.done)
+
+		// Wait for running sends.
+		b.send.Lock()
+		defer b.send.Unlock()
+
+		close(b.events)
+		close(b.errors)
+	})
+
+	return nil
+}
+
+// Create sends a Create event for a file.
+func (b *FakeBackend) Create(name string) {
+	b.Send(observer.WatchEvent{Name: name, Op: observer.Create})
+}
+
+// Write sends a Write event for a file.
+func (b *FakeBackend) Write(name string) {
+	b.Send(observer.WatchEvent{Name: name, Op: observer.Write})
+}
+
+// RemoveFile sends a Remove event for a file.
+func (b *FakeBackend) RemoveFile(name string) {
+	b.Send(observer.WatchEvent{Name: name, Op: observer.Remove})
+}
+
+// Rename sends a Rename event for a file.
+func (b *FakeBackend) Rename(name string) {
+	b.Send(observer.WatchEvent{Name: name, Op: observer.Rename})
+}
+
+// Move sends a Rename event for the old file name, and a Create event for
+// the new file name, both events have the same Cookie, same as a file
+// watcher reporting rename cookies.
+func (b *FakeBackend) Move(oldName string, newName string) {
+	b.mutex.Lock()
+	b.cookie++
+	cookie := b.cookie
+	b.mutex.Unlock()
+
+	b.Send(observer.WatchEvent{Name: oldName, Op: observer.Rename, Cookie: cookie})
+	b.Send(observer.WatchEvent{Name: newName, Op: observer.Create, Cookie: cookie})
+}
+
+// CloseWrite sends a CloseWrite event for a file.
+func (b *FakeBackend) CloseWrite(name string) {
+	b.Send(observer.WatchEvent{Name: name, Op: observer.CloseWrite})
+}
+
+// Chmod sends a Chmod event for a file.
+func (b *FakeBackend) Chmod(name string) {
+	b.Send(observer.WatchEvent{Name: name, Op: observer.Chmod})
+}
+
+// Send sends an event, if the file directory is watched. The event name is
+// the watched directory joined with the file base name, same as a real file
+// watcher, e.g. "a.conf" is sent as "./a.conf". Send blocks until the event
+// is handled by the observer, buffered events and timers are set, but
+// listeners may still be running.
+func (b *FakeBackend) Send(e observer.WatchEvent) {
+	b.send.Lock()
+	defer b.send.Unlock()
+
+	if b.closed() {
+		return
+	}
+
+	dir := filepath.Dir(e.Name)
+	if !b.Watched(dir) {
+		return
+	}
+	e.Name = fmt.Sprintf("%s%s%s", dir, string(filepath.Separator), filepath.Base(e.Name))
+
+	select {
+	case b.events <- e:
+		b.wait()
+	case <-b.done:
+	}
+}
+
+// Error sends a file watcher error, Error blocks until the error is handled
+// by the observer.
+func (b *FakeBackend) Error(err error) {
+	b.send.Lock()
+	defer b.send.Unlock()
+
+	if b.closed() {
+		return
+	}
+
+	select {
+	case b.errors <- err:
+		b.wait()
+	case <-b.done:
+	}
+}
+
+// Handled is called by the observer after an event or an error is handled.
+func (b *FakeBackend) Handled() {
+	select {
+	case b.handled <- struct{}{}:
+	case <-b.done:
+	}
+}
+
+// wait blocks until the observer handled the last sent event or error.
+func (b *FakeBackend) wait() {
+	select {
+	case <-b.handled:
+	case <-b.done:
+	}
+}
+
+// closed returns a boolean asserting whether the backend is closed.
+func (b *FakeBackend) closed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observertest implements a fake file watcher backend and a virtual
+// clock for testing code using the observer package.
+package observertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yaacov/observer/observer"
+)
+
+// FakeClock is a virtual observer.Clock, time moves only when calling
+// Advance, and timers are fired synchronously by Advance.
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	mutex  sync.Mutex
+}
+
+// fakeTimer is a timer created by a FakeClock.
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	f     func()
+}
+
+// NewFakeClock returns a new virtual clock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the virtual clock current time.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+// AfterFunc calls f when the virtual clock is advanced by d.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) observer.Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	t := &fakeTimer{clock: c, when: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+
+	return t
+}
+
+// Timers returns the number of timers waiting to fire.
+func (c *FakeClock) Timers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.timers)
+}
+
+// Advance moves the virtual clock forward by d, and calls the functions
+// of timers that are due, in order of their fire time.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	end := c.now.Add(d)
+	c.mutex.Unlock()
+
+	for {
+		t := c.next(end)
+		if t == nil {
+			break
+		}
+
+		// Run the timer function without holding the clock lock, timer
+		// functions may create new timers.
+		t.f()
+	}
+
+	c.mutex.Lock()
+	c.now = end
+	c.mutex.Unlock()
+}
+
+// next removes and returns the first timer due before end, and moves the
+// clock to the timer fire time.
+func (c *FakeClock) next(end time.Time) *fakeTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].when.Before(c.timers[j].when)
+	})
+
+	if len(c.timers) == 0 || c.timers[0].when.After(end) {
+		return nil
+	}
+
+	t := c.timers[0]
+	c.timers = c.timers[1:]
+	if t.when.After(c.now) {
+		c.now = t.when
+	}
+
+	return t
+}
+
+// Stop prevents the timer from firing, it returns false if the timer
+// already fired or was stopped.
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
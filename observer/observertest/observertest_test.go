@@ -0,0 +1,426 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observertest implements a fake file watcher backend and a virtual
+// clock for testing code using the observer package.
+package observertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yaacov/observer/observer"
+)
+
+func TestFakeBackend(t *testing.T) {
+	var o observer.Observer
+
+	fake := NewFakeBackend()
+	o.SetBackend(fake)
+	o.Watch([]string{"a.conf"})
+	defer o.Close()
+
+	events := make(chan observer.WatchEvent, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.(observer.WatchEvent)
+	})
+
+	// Files in directories that are not watched are ignored.
+	fake.Write("/etc/a.conf")
+	fake.Write("a.conf")
+
+	e := <-events
+	if e.Name != "./a.conf" || e.Op != observer.Write {
+		t.Errorf("error sending fake events, received %v.", e)
+	}
+
+	if !fake.Watched(".") || fake.Watched("/etc") {
+		t.Error("error getting watched directories.")
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	var o observer.Observer
+
+	now := time.Now()
+	clock := NewFakeClock(now)
+	fake := NewFakeBackend()
+
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetBufferDuration(time.Second)
+	o.Watch([]string{"a.conf", "b.conf"})
+	defer o.Close()
+
+	events := make(chan []interface{}, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.([]interface{})
+	})
+
+	// Each send blocks until the event is handled.
+	fake.Write("a.conf")
+	fake.Write("b.conf")
+
+	if clock.Timers() != 1 {
+		t.Fatal("error setting a buffer timer.")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if clock.Timers() != 1 || !clock.Now().Equal(now.Add(500*time.Millisecond)) {
+		t.Fatal("error advancing the clock before the buffer timer.")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if clock.Timers() != 0 {
+		t.Fatal("error firing the buffer timer.")
+	}
+
+	if batch := <-events; len(batch) != 2 {
+		t.Errorf("error sending buffered events, received %v.", batch)
+	}
+}
+
+func TestFakeRename(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetRenameTracking(time.Second)
+	o.WatchOps([]string{"*.conf"}, observer.Rename)
+	defer o.Close()
+
+	events := make(chan observer.WatchEvent, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.(observer.WatchEvent)
+	})
+
+	// Moved files are sent as one event.
+	fake.Move("a.conf", "b.conf")
+
+	e := <-events
+	if e.Name != "./b.conf" || e.OldName != "./a.conf" || e.Op != observer.Rename {
+		t.Errorf("error tracking renamed file, received %v.", e)
+	}
+
+	// Files moved out of the watched directories are sent after the window.
+	fake.Rename("c.conf")
+	if clock.Timers() != 1 {
+		t.Fatal("error setting a rename timer.")
+	}
+	clock.Advance(time.Second)
+
+	e = <-events
+	if e.Name != "./c.conf" || e.OldName != "" || e.Op != observer.Rename {
+		t.Errorf("error sending renamed file, received %v.", e)
+	}
+}
+
+func TestFakeCloseWrite(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetStableDuration(time.Second)
+	o.Watch([]string{"a.bin"})
+	defer o.Close()
+
+	events := make(chan observer.WatchEvent, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.(observer.WatchEvent)
+	})
+
+	// Writes are held until the writer closes the file.
+	fake.Create("a.bin")
+	fake.Write("a.bin")
+	fake.Write("a.bin")
+	fake.CloseWrite("a.bin")
+
+	e := <-events
+	if e.Name != "./a.bin" || e.Op != observer.Create|observer.Write {
+		t.Errorf("error holding written file, received %v.", e)
+	}
+
+	if clock.Timers() != 0 {
+		t.Error("error stopping the stable timer.")
+	}
+}
+
+func TestFakeStableDuration(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetStableDuration(time.Second)
+	o.Watch([]string{"*.bin"})
+	defer o.Close()
+
+	events := make(chan observer.WatchEvent, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.(observer.WatchEvent)
+	})
+
+	// Writes are sent after the quiet duration, files are not on disk.
+	fake.Write("a.bin")
+	clock.Advance(time.Second)
+
+	e := <-events
+	if e.Name != "./a.bin" || e.Op != observer.Write {
+		t.Errorf("error sending held written file, received %v.", e)
+	}
+
+	// Writes of removed files are dropped, the Remove event is sent.
+	fake.Write("b.bin")
+	fake.RemoveFile("b.bin")
+	clock.Advance(time.Second)
+
+	e = <-events
+	if e.Name != "./b.bin" || e.Op != observer.Remove {
+		t.Errorf("error sending removed file, received %v.", e)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("error dropping held events of removed file, received %v.", e)
+	default:
+	}
+}
+
+func TestFakeRenameDefaultOps(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetRenameTracking(time.Second)
+	o.Watch([]string{"*.conf"})
+	defer o.Close()
+
+	events := make(chan observer.WatchEvent, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.(observer.WatchEvent)
+	})
+
+	// Files moved into place are sent as created files when renames are
+	// not watched.
+	fake.Move("a.tmp", "b.conf")
+
+	e := <-events
+	if e.Name != "./b.conf" || e.OldName != "./a.tmp" || e.Op != observer.Create {
+		t.Errorf("error sending file moved into place, received %v.", e)
+	}
+}
+
+func TestFakeBufferKey(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetBufferDuration(time.Second)
+	o.SetBufferKey(observer.PathKey)
+	o.Watch([]string{"a.conf", "b.css"})
+	defer o.Close()
+
+	events := make(chan []interface{}, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.([]interface{})
+	})
+
+	fake.Write("a.conf")
+	fake.Write("a.conf")
+	fake.Write("b.css")
+
+	if clock.Timers() != 2 {
+		t.Fatal("error setting a buffer timer per key.")
+	}
+	clock.Advance(time.Second)
+
+	// Each key is sent in it's own batch, repeated events are sent once.
+	for i := 0; i < 2; i++ {
+		batch := <-events
+		if len(batch) != 1 {
+			t.Errorf("error sending keyed buffered events, received %v.", batch)
+		}
+	}
+}
+
+func TestFakeDebounceMode(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetBufferDuration(time.Second)
+	o.SetDebounceMode(observer.Trailing)
+	o.SetMaxWait(2500 * time.Millisecond)
+	o.Watch([]string{"a.conf"})
+	defer o.Close()
+
+	events := make(chan []interface{}, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.([]interface{})
+	})
+
+	// Each event restarts the buffer duration.
+	fake.Write("a.conf")
+	for i := 0; i < 3; i++ {
+		clock.Advance(800 * time.Millisecond)
+		fake.Write("a.conf")
+	}
+
+	select {
+	case batch := <-events:
+		t.Fatalf("error restarting the buffer duration, received %v.", batch)
+	default:
+	}
+
+	// Events are not delayed more than the maximum wait.
+	clock.Advance(100 * time.Millisecond)
+	if batch := <-events; len(batch) != 4 {
+		t.Errorf("error sending events after the maximum wait, received %v.", batch)
+	}
+}
+
+func TestFakeLeadingMode(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetBufferDuration(time.Second)
+	o.SetDebounceMode(observer.Leading)
+	o.Watch([]string{"a.conf"})
+	defer o.Close()
+
+	events := make(chan []interface{}, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.([]interface{})
+	})
+
+	// The first event is sent immediately, the second is dropped.
+	fake.Write("a.conf")
+	fake.Write("a.conf")
+
+	if batch := <-events; len(batch) != 1 {
+		t.Errorf("error sending the leading event, received %v.", batch)
+	}
+
+	// After the quiet duration the next event is sent immediately.
+	clock.Advance(time.Second)
+	fake.Write("a.conf")
+
+	if batch := <-events; len(batch) != 1 {
+		t.Errorf("error sending the leading event, received %v.", batch)
+	}
+
+	select {
+	case batch := <-events:
+		t.Errorf("error dropping events, received %v.", batch)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestFakeListenerTTL(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	o.SetClock(clock)
+	o.Open()
+	defer o.Close()
+
+	events := make(chan interface{}, 10)
+	s := o.AddListenerTTL(time.Minute, func(e interface{}) {
+		events <- e
+	})
+
+	o.Emit("before")
+	if e := <-events; e != "before" {
+		t.Errorf("error running listener before ttl, received %v.", e)
+	}
+
+	// The listener is removed when ttl expires.
+	clock.Advance(time.Minute)
+	if err := s.Unsubscribe(); err == nil {
+		t.Error("error removing listener after ttl.")
+	}
+}
+
+func TestFakeSaveCoalescing(t *testing.T) {
+	var o observer.Observer
+
+	clock := NewFakeClock(time.Now())
+	fake := NewFakeBackend()
+	o.SetClock(clock)
+	o.SetBackend(fake)
+	o.SetSaveCoalescing(time.Second)
+	o.Watch([]string{"*.conf"})
+	defer o.Close()
+
+	events := make(chan observer.WatchEvent, 10)
+	o.AddListener(func(e interface{}) {
+		events <- e.(observer.WatchEvent)
+	})
+
+	// The coalesced event is derived from the file operations, files are
+	// not on disk.
+	steps := []struct {
+		send     func(string)
+		expected observer.Op
+	}{
+		{fake.Create, observer.Create},
+		{fake.Write, observer.Modified},
+		{fake.RemoveFile, observer.Remove},
+	}
+	for _, step := range steps {
+		step.send("a.conf")
+		clock.Advance(time.Second)
+
+		if e := <-events; e.Name != "./a.conf" || e.Op != step.expected {
+			t.Errorf("error coalescing fake file events, received %v.", e)
+		}
+	}
+}
+
+func TestFakePatternOrder(t *testing.T) {
+	var o observer.Observer
+
+	fake := NewFakeBackend()
+	o.SetBackend(fake)
+	o.Watch([]string{"*.conf", "a.*"})
+	defer o.Close()
+
+	events := make(chan observer.WatchEvent, 100)
+	o.AddListener(func(e interface{}) {
+		events <- e.(observer.WatchEvent)
+	})
+
+	// Overlapping patterns report the first added pattern.
+	for i := 0; i < 20; i++ {
+		fake.Write("a.conf")
+
+		if e := <-events; e.Pattern != "./*.conf" {
+			t.Fatalf("error reporting the first added pattern, received %v.", e.Pattern)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"time"
+)
+
+// Once adds a listener function to run on the first event, the listener
+// is removed after it is called.
+func (o *Observer) Once(l Listener) Subscription {
+	return o.AddListenerN(1, l)
+}
+
+// AddListenerN adds a listener function to run on the next n events, the
+// listener is removed after it is called n times, n less than one is
+// treated as one.
+func (o *Observer) AddListenerN(n int, l Listener) Subscription {
+	return o.addListenerN(n, limitedListener(l, nil))
+}
+
+// AddListenerTTL adds a listener function to run on events during ttl, the
+// listener is removed when ttl expires, ttl less than a nanosecond is
+// treated as a nanosecond.
+func (o *Observer) AddListenerTTL(ttl time.Duration, l Listener) Subscription {
+	return o.addListenerTTL(ttl, limitedListener(l, nil))
+}
+
+// addListenerN adds a listener removed after it is called n times.
+func (o *Observer) addListenerN(n int, l listener) Subscription {
+	if n < 1 {
+		n = 1
+	}
+
+	return o.addLimitedListener(l, n, 0)
+}
+
+// addListenerTTL adds a listener removed when ttl expires.
+func (o *Observer) addListenerTTL(ttl time.Duration, l listener) Subscription {
+	if ttl < time.Nanosecond {
+		ttl = time.Nanosecond
+	}
+
+	return o.addLimitedListener(l, 0, ttl)
+}
+
+// limitedListener returns a listener of all topics running l on the events
+// accepted by accept, a nil accept accepts all events.
+func limitedListener(l Listener, accept func(interface{}) bool) listener {
+	return listener{
+		topic: AllTopics,
+		fn: func(ctx context.Context, e interface{}) error {
+			l(e)
+			return nil
+		},
+		accept: accept,
+	}
+}
+
+// addLimitedListener adds a listener to run on events emitted on matching
+// topics, the listener is removed after it is called n times, or when ttl
+// expires, zero n or ttl means no limit.
+func (o *Observer) addLimitedListener(l listener, n int, ttl time.Duration) Subscription {
+	o.init()
+
+	// Lock:
+	// 1. operations on array listeners.
+	// 2. operations on the remaining calls map.
+	// 3. operations on the expiry timers map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.lastID++
+	l.id = o.lastID
+	o.listeners = append(o.listeners, l)
+	s := Subscription{id: o.lastID, o: o}
+
+	if n > 0 {
+		if o.remaining == nil {
+			o.remaining = make(map[uint64]int)
+		}
+		o.remaining[s.id] = n
+	}
+
+	if ttl > 0 {
+		if o.expiry == nil {
+			o.expiry = make(map[uint64]Timer)
+		}
+		o.expiry[s.id] = o.clock.AfterFunc(ttl, func() {
+			s.Unsubscribe()
+		})
+	}
+
+	return s
+}
+
+// WaitFor blocks until an event matching predicate is emitted, and returns
+// the event, a nil predicate matches any event. It will return the ctx
+// error if ctx is done, and ErrClosed if the observer is closed.
+//
+// In buffered mode predicate receives the array of buffered events.
+func (o *Observer) WaitFor(ctx context.Context, predicate func(interface{}) bool) (interface{}, error) {
+	o.init()
+
+	events := make(chan interface{}, 1)
+	s := o.AddListener(func(e interface{}) {
+		if predicate != nil && !predicate(e) {
+			return
+		}
+
+		// Keep only the first matching event.
+		select {
+		case events <- e:
+		default:
+		}
+	})
+	defer s.Unsubscribe()
+
+	select {
+	case e := <-events:
+		return e, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-o.ctx.Done():
+		return nil, ErrClosed
+	}
+}
+
+// consume counts one call of a listener added using AddListenerN, and
+// removes the listener after it's last call.
+func (o *Observer) consume(l listener) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using consume must be locked
+	// for operations using o.remaining and o.listeners.
+	n, ok := o.remaining[l.id]
+	if !ok {
+		return
+	}
+
+	if n > 1 {
+		o.remaining[l.id] = n - 1
+		return
+	}
+
+	o.removeListener(l.id)
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAddListenerN(t *testing.T) {
+	var o Observer
+
+	events := make(chan interface{}, 10)
+
+	o.Open()
+	defer o.Close()
+
+	once := o.Once(func(e interface{}) {
+		events <- e
+	})
+	twice := o.AddListenerN(2, func(e interface{}) {
+		events <- e
+	})
+
+	for i := 0; i < 3; i++ {
+		o.Emit(i)
+	}
+
+	// Wait for the emitted events to be handled.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	o.Shutdown(ctx)
+
+	if len(events) != 3 {
+		t.Errorf("error limiting listener calls, received %d events.", len(events))
+	}
+	if once.Unsubscribe() == nil || twice.Unsubscribe() == nil {
+		t.Error("error removing listeners after their last call.")
+	}
+}
+
+func TestOnceConcurrentEmit(t *testing.T) {
+	var o Observer
+
+	events := make(chan interface{}, 100)
+
+	o.Open()
+
+	// Emit events while the listener is added.
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			o.Emit(i)
+		}
+		done <- true
+	}()
+
+	o.Once(func(e interface{}) {
+		events <- e
+	})
+	<-done
+
+	// Wait for the emitted events to be handled.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	o.Shutdown(ctx)
+
+	if len(events) > 1 {
+		t.Errorf("error calling a once listener more than once, received %d events.", len(events))
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	var o Observer
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_wait_for.conf")
+
+	o.Watch([]string{tmpfn})
+	defer o.Close()
+
+	// Create the file after WaitFor is waiting.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		ioutil.WriteFile(tmpfn, []byte("content"), 0666)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	e, err := o.WaitFor(ctx, func(e interface{}) bool {
+		we, ok := e.(WatchEvent)
+		return ok && we.Op&Create == Create
+	})
+	if err != nil || e.(WatchEvent).Name != tmpfn {
+		t.Errorf("error waiting for created file, received %v %v.", e, err)
+	}
+
+	// WaitFor returns the ctx error.
+	var quiet Observer
+	quiet.Open()
+	defer quiet.Close()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := quiet.WaitFor(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("error waiting until ctx is done, received %v.", err)
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDropped is the error of EmitSync listener calls dropped by the
+// dispatch pool.
+var ErrDropped = errors.New("Listener call dropped.")
+
+// Backpressure is the policy used when the dispatch pool queue is full.
+type Backpressure int
+
+// These are the dispatch pool backpressure policies.
+const (
+	// Block the emitter until the queue has room.
+	Block Backpressure = iota
+
+	// DropOldest drop the oldest queued listener call.
+	DropOldest
+
+	// DropNewest drop the new listener call.
+	DropNewest
+
+	// Coalesce merge a listener call with an equal queued call of the same
+	// listener, o/w block the emitter until the queue has room.
+	Coalesce
+)
+
+// Stats is the listener dispatch counters.
+type Stats struct {
+	Dispatched uint64 // Listener calls dispatched.
+	Dropped    uint64 // Listener calls dropped by the dispatch pool.
+	Coalesced  uint64 // Listener calls merged with queued calls.
+	Queued     int    // Listener calls waiting in the dispatch pool queue.
+}
+
+// job is a listener call waiting in the dispatch pool queue.
+type job struct {
+	ctx   context.Context
+	l     listener
+	event interface{}
+}
+
+// pool is a bounded queue of listener calls, run by a fixed number of
+// worker goroutines.
+type pool struct {
+	workers   int
+	size      int
+	policy    Backpressure
+	jobs      []job
+	started   bool
+	closed    bool
+	dropped   uint64
+	coalesced uint64
+	mutex     sync.Mutex
+	notEmpty  *sync.Cond
+	notFull   *sync.Cond
+}
+
+// SetDispatchPool set a dispatch pool of workers goroutines running the
+// listener calls, with a queue of size listener calls, when the queue is
+// full the policy is used to block the emitter or drop listener calls.
+// The dispatch pool is used in Concurrent delivery mode, it will return
+// an error if the pool is already set, or if the delivery mode is Sequential.
+func (o *Observer) SetDispatchPool(workers int, size int, policy Backpressure) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on the dispatch pool.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if workers < 1 || size < 1 {
+		return fmt.Errorf("Invalid dispatch pool size.")
+	}
+
+	if o.pool != nil {
+		return fmt.Errorf("Dispatch pool already set.")
+	}
+
+	if o.deliveryMode == Sequential {
+		return fmt.Errorf("Dispatch pool can not be used in Sequential delivery mode.")
+	}
+
+	p := &pool{workers: workers, size: size, policy: policy}
+	p.notEmpty = sync.NewCond(&p.mutex)
+	p.notFull = sync.NewCond(&p.mutex)
+	o.pool = p
+
+	return nil
+}
+
+// Stats returns the listener dispatch counters.
+func (o *Observer) Stats() (s Stats) {
+	o.init()
+
+	// Lock:
+	// 1. operations on the dispatch counters.
+	o.mutex.Lock()
+	s.Dispatched = o.dispatched
+	p := o.pool
+	o.mutex.Unlock()
+
+	if p != nil {
+		p.mutex.Lock()
+		s.Dropped = p.dropped
+		s.Coalesced = p.coalesced
+		s.Queued = len(p.jobs)
+		p.mutex.Unlock()
+	}
+
+	return
+}
+
+// unlock release the observer mutex, and submit the listener calls
+// dispatched while locked to the dispatch pool, submitting may block the
+// emitter so it is done after the mutex is released.
+func (o *Observer) unlock() {
+	jobs := o.outbox
+	o.outbox = nil
+	p := o.pool
+	o.mutex.Unlock()
+
+	for _, j := range jobs {
+		p.submit(o, j)
+	}
+}
+
+// submit add a listener call to the queue, using the backpressure policy
+// when the queue is full.
+//
+// NOTE: the pool mutex is locked before the observer mutex, submit must
+// not be called while the observer mutex is locked.
+func (p *pool) submit(o *Observer, j job) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Start the workers on first call.
+	if !p.started {
+		p.started = true
+		for i := 0; i < p.workers; i++ {
+			go p.work(o)
+		}
+	}
+
+	// Merge with an equal queued call of the same listener.
+	if p.policy == Coalesce {
+		for _, queued := range p.jobs {
+			if queued.l.id == j.l.id && sameEvent(queued.event, j.event) {
+				p.coalesced++
+				o.done(j.l)
+
+				return
+			}
+		}
+	}
+
+	for len(p.jobs) >= p.size && !p.closed {
+		switch p.policy {
+		case DropNewest:
+			p.dropped++
+			o.drop(j)
+
+			return
+		case DropOldest:
+			oldest := p.jobs[0]
+			p.jobs = p.jobs[1:]
+			p.dropped++
+			o.drop(oldest)
+		default:
+			p.notFull.Wait()
+		}
+	}
+
+	// Calls submitted after the pool is closed are dropped.
+	if p.closed {
+		p.dropped++
+		o.drop(j)
+
+		return
+	}
+
+	p.jobs = append(p.jobs, j)
+	p.notEmpty.Signal()
+}
+
+// work runs queued listener calls until the pool is closed.
+func (p *pool) work(o *Observer) {
+	for {
+		p.mutex.Lock()
+		for len(p.jobs) == 0 && !p.closed {
+			p.notEmpty.Wait()
+		}
+
+		if p.closed {
+			p.mutex.Unlock()
+			return
+		}
+
+		j := p.jobs[0]
+		p.jobs = p.jobs[1:]
+		p.notFull.Signal()
+		p.mutex.Unlock()
+
+		o.run(j.ctx, j.l, j.event)
+		o.done(j.l)
+	}
+}
+
+// close stop the workers, and drop queued listener calls.
+//
+// NOTE: the pool mutex is locked before the observer mutex, close must
+// not be called while the observer mutex is locked.
+func (p *pool) close(o *Observer) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+	p.dropped += uint64(len(p.jobs))
+	for _, j := range p.jobs {
+		o.drop(j)
+	}
+	p.jobs = nil
+
+	p.notEmpty.Broadcast()
+	p.notFull.Broadcast()
+}
+
+// drop marks a dropped listener call as finished, calls dispatched by
+// EmitSync fail with ErrDropped.
+func (o *Observer) drop(j job) {
+	if c, ok := j.event.(*syncCall); ok {
+		c.results <- &ListenerError{ID: j.l.id, Err: ErrDropped}
+	}
+
+	o.done(j.l)
+}
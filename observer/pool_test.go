@@ -0,0 +1,167 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitStats waits for the observer counters to match expected.
+func waitStats(t *testing.T, o *Observer, expected Stats) {
+	deadline := time.Now().Add(5 * time.Second)
+	for o.Stats() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("error counting dispatched events, received %+v.", o.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSetDispatchPool(t *testing.T) {
+	policies := []struct {
+		policy   Backpressure
+		events   []interface{}
+		expected Stats
+	}{
+		{DropNewest, []interface{}{1, 2, 3}, Stats{Dispatched: 3, Dropped: 1, Queued: 1}},
+		{DropOldest, []interface{}{1, 2, 3}, Stats{Dispatched: 3, Dropped: 1, Queued: 1}},
+		{Coalesce, []interface{}{1, 2, 2}, Stats{Dispatched: 3, Coalesced: 1, Queued: 1}},
+	}
+
+	for _, p := range policies {
+		var o Observer
+
+		started := make(chan struct{}, 10)
+		release := make(chan struct{})
+
+		if err := o.SetDispatchPool(1, 1, p.policy); err != nil {
+			t.Fatal("error setting a dispatch pool.")
+		}
+		if err := o.SetDispatchPool(1, 1, p.policy); err == nil {
+			t.Error("no error setting a second dispatch pool.")
+		}
+		o.Open()
+
+		o.AddListener(func(e interface{}) {
+			started <- struct{}{}
+			<-release
+		})
+
+		// The first event is running, the second is queued.
+		o.Emit(p.events[0])
+		<-started
+		o.Emit(p.events[1])
+		o.Emit(p.events[2])
+
+		waitStats(t, &o, p.expected)
+		close(release)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := o.Shutdown(ctx); err != nil {
+			t.Errorf("error waiting for dispatched events: %v.", err)
+		}
+		cancel()
+	}
+}
+
+func TestDispatchPoolBlock(t *testing.T) {
+	var o Observer
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+
+	o.SetDispatchPool(1, 1, Block)
+	o.SetQueueSize(1)
+	o.Open()
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		started <- struct{}{}
+		<-release
+	})
+
+	// The first event is running, the second is queued, and the third
+	// blocks the event loop, so the fourth fills the event queue.
+	o.Emit(1)
+	<-started
+	o.Emit(2)
+	o.Emit(3)
+	o.Emit(4)
+
+	if err := o.TryEmit(5); err != ErrWouldBlock {
+		t.Error("error blocking the emitter on a full dispatch pool.")
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("error running blocked events.")
+		}
+	}
+
+	waitStats(t, &o, Stats{Dispatched: 4})
+}
+
+func TestDispatchPoolSequential(t *testing.T) {
+	var o, p Observer
+
+	o.SetDeliveryMode(Sequential)
+	if err := o.SetDispatchPool(1, 1, Block); err == nil {
+		t.Error("no error setting a dispatch pool in Sequential delivery mode.")
+	}
+
+	p.SetDispatchPool(1, 1, Block)
+	if err := p.SetDeliveryMode(Sequential); err == nil {
+		t.Error("no error setting Sequential delivery mode with a dispatch pool.")
+	}
+}
+
+func TestDispatchPoolClose(t *testing.T) {
+	var o Observer
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+
+	o.SetDispatchPool(1, 1, Block)
+	o.Open()
+
+	o.AddListener(func(e interface{}) {
+		started <- struct{}{}
+		<-release
+	})
+
+	// The first event is running, the second is queued and dropped on close.
+	o.Emit(1)
+	<-started
+	o.Emit(2)
+	waitStats(t, &o, Stats{Dispatched: 2, Queued: 1})
+
+	o.Close()
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(o.runningListeners()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("error finishing dropped listener calls, running %v.", o.runningListeners())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
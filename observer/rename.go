@@ -0,0 +1,153 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"time"
+)
+
+// pendingRename is a Rename event waiting for the Create event of the
+// file new name.
+type pendingRename struct {
+	event WatchEvent
+	timer Timer
+}
+
+// SetRenameTracking set the rename tracking window, when a file is renamed
+// the file watcher sends a Rename event with the old name and a Create event
+// with the new name, rename tracking merge the two events into one Rename
+// event with OldName and Name.
+//
+// Events are correlated using the event Cookie when the backend sets it,
+// o/w a Rename event is merged with the first Create event received within
+// the window. A Rename event without a matching Create event, e.g. a file
+// moved out of the watched directories, is sent after the window.
+// When the Rename operation is not watched for the old or the new name,
+// e.g. when using Watch, the Create event of a watched file is sent with
+// OldName instead of the merged event.
+// Zero window disable rename tracking.
+func (o *Observer) SetRenameTracking(window time.Duration) {
+	o.init()
+
+	// Lock:
+	// 1. operations on the renames array.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.renameWindow = window
+}
+
+// trackRename holds Rename events until the matching Create event is
+// received, it returns the event to send and a boolean asserting whether
+// there is an event to send now.
+func (o *Observer) trackRename(e WatchEvent) (WatchEvent, bool) {
+	// Lock:
+	// 1. operations on the renames array.
+	// 2. operations using the watchPatterns set (matchName).
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.renameWindow == 0 {
+		return e, true
+	}
+
+	switch {
+	case e.Op&Create == Create:
+		for i, p := range o.renames {
+			// Match cookies, events without a cookie match the first
+			// pending rename without a cookie.
+			if p.event.Cookie != e.Cookie {
+				continue
+			}
+
+			p.timer.Stop()
+			o.renames = append(o.renames[:i:i], o.renames[i+1:]...)
+
+			// Files moved into place are sent as created files when
+			// renames are not watched, e.g. when using Watch.
+			if !o.matchName(e.Name, Rename) && !o.matchName(p.event.Name, Rename) && o.matchName(e.Name, Create) {
+				return WatchEvent{Name: e.Name, OldName: p.event.Name, Op: Create, Cookie: e.Cookie}, true
+			}
+
+			return WatchEvent{Name: e.Name, OldName: p.event.Name, Op: Rename, Cookie: e.Cookie}, true
+		}
+	case e.Op&Rename == Rename:
+		p := &pendingRename{event: e}
+		p.timer = o.clock.AfterFunc(o.renameWindow, func() {
+			o.expireRename(p)
+		})
+		o.renames = append(o.renames, p)
+
+		return e, false
+	}
+
+	return e, true
+}
+
+// expireRename send a Rename event that did not match a Create event.
+func (o *Observer) expireRename(p *pendingRename) {
+	// Lock:
+	// 1. operations on the renames array.
+	o.mutex.Lock()
+	found := false
+	for i, r := range o.renames {
+		if r == p {
+			o.renames = append(o.renames[:i:i], o.renames[i+1:]...)
+			found = true
+			break
+		}
+	}
+	o.mutex.Unlock()
+
+	// The rename was already matched or flushed.
+	if !found {
+		return
+	}
+
+	if !o.coalesceSave(p.event) {
+		o.handleWatchEvent(p.event)
+	}
+}
+
+// flushRenames send all pending Rename events now.
+func (o *Observer) flushRenames() {
+	// Lock:
+	// 1. operations on the renames array.
+	o.mutex.Lock()
+	renames := o.renames
+	o.stopRenames()
+	o.mutex.Unlock()
+
+	for _, p := range renames {
+		if !o.coalesceSave(p.event) {
+			o.handleWatchEvent(p.event)
+		}
+	}
+}
+
+// stopRenames stop the pending renames timers, and drop pending renames.
+func (o *Observer) stopRenames() {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using stopRenames must be locked
+	// for operations using o.renames.
+	for _, p := range o.renames {
+		p.timer.Stop()
+	}
+
+	o.renames = nil
+}
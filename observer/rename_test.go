@@ -0,0 +1,67 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetRenameTracking(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	oldfn := filepath.Join(dir, "test_rename_old.txt")
+	newfn := filepath.Join(dir, "test_rename_new.txt")
+	if err := ioutil.WriteFile(oldfn, []byte("content"), 0666); err != nil {
+		t.Error("error writing to temp file.")
+	}
+
+	// watch renamed files using a polling backend
+	b := NewPollingBackend(0)
+	o.SetBackend(b)
+	o.SetRenameTracking(5 * time.Second)
+	o.WatchOps([]string{filepath.Join(dir, "*.txt")}, Rename)
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	if err := os.Rename(oldfn, newfn); err != nil {
+		t.Error("error renaming temp file.")
+	}
+	b.Poll()
+
+	select {
+	case e := <-events:
+		if e.Name != newfn || e.OldName != oldfn || e.Op != Rename {
+			t.Errorf("error tracking renamed file, received %v.", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error tracking renamed file.")
+	}
+}
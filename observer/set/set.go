@@ -22,10 +22,11 @@ import (
 )
 
 // Set objects are collections of strings. A value in the Set may only occur once,
-// it is unique in the Set's collection.
+// it is unique in the Set's collection. Values are kept in insertion order.
 type Set struct {
-	set   map[string]struct{}
-	mutex *sync.Mutex
+	set    map[string]struct{}
+	values []string
+	mutex  *sync.Mutex
 }
 
 // Add appends a new element with the given value to the Set object.
@@ -49,6 +50,32 @@ func (s *Set) Add(v string) error {
 	}
 
 	s.set[v] = struct{}{}
+	s.values = append(s.values, v)
+	return nil
+}
+
+// Remove deletes the element with the given value from the Set object.
+// It returns an error if the value is not in set.
+func (s *Set) Remove(v string) error {
+	// Check for mutex
+	s.init()
+
+	// Lock this function
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Check for value exist.
+	if _, ok := s.set[v]; !ok {
+		return fmt.Errorf("Value not in set.")
+	}
+
+	delete(s.set, v)
+	for i, value := range s.values {
+		if value == v {
+			s.values = append(s.values[:i:i], s.values[i+1:]...)
+			break
+		}
+	}
 	return nil
 }
 
@@ -62,10 +89,11 @@ func (s *Set) Clear() {
 	defer s.mutex.Unlock()
 
 	s.set = nil
+	s.values = nil
 }
 
 // Values returns a new list object that contains the values for each element
-// in the Set object.
+// in the Set object, in insertion order.
 func (s *Set) Values() (keys []string) {
 	// Check for mutex
 	s.init()
@@ -74,10 +102,8 @@ func (s *Set) Values() (keys []string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	keys = make([]string, 0, len(s.set))
-	for k := range s.set {
-		keys = append(keys, k)
-	}
+	keys = make([]string, len(s.values))
+	copy(keys, s.values)
 
 	return
 }
@@ -44,6 +44,29 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+func TestRemove(t *testing.T) {
+	var s Set
+	var e error
+
+	e = s.Remove("hello")
+	if e == nil {
+		t.Error("error removing a value from an empty Set.")
+	}
+
+	s.Add("hello")
+	s.Add("world")
+
+	e = s.Remove("hello")
+	if len(s.Values()) != 1 || e != nil || s.Has("hello") {
+		t.Error("error removing a value from Set.")
+	}
+
+	e = s.Remove("hello")
+	if len(s.Values()) != 1 || e == nil {
+		t.Error("error removing a missing value from Set.")
+	}
+}
+
 func TestClear(t *testing.T) {
 	var s Set
 
@@ -86,3 +109,17 @@ func TestHas(t *testing.T) {
 		t.Error("error checking value is not in Set.")
 	}
 }
+
+func TestValuesOrder(t *testing.T) {
+	var s Set
+
+	s.Add("world")
+	s.Add("hello")
+	s.Add("again")
+	s.Remove("hello")
+
+	values := s.Values()
+	if len(values) != 2 || values[0] != "world" || values[1] != "again" {
+		t.Errorf("error keeping Set values in insertion order, received %v.", values)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ShutdownError is returned by Shutdown when listeners did not finish
+// before the context is done.
+type ShutdownError struct {
+	Listeners []uint64 // Subscription ids of the listeners still running.
+	Err       error    // The context error.
+}
+
+// Error returns the shutdown error message.
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("%d listeners did not finish: %v %v", len(e.Listeners), e.Listeners, e.Err)
+}
+
+// Unwrap returns the context error.
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// Shutdown gracefully close the observer, new events are dropped, buffered
+// events are sent immediately, and Shutdown waits for running listeners
+// until ctx is done, then the observer is closed.
+//
+// If listeners did not finish before ctx is done, Shutdown returns a
+// *ShutdownError with the listeners subscription ids.
+func (o *Observer) Shutdown(ctx context.Context) error {
+	o.init()
+
+	// Stop accepting new events.
+	o.mutex.Lock()
+	opened := o.state == StateOpen
+	if o.state != StateClosed {
+		o.state = StateClosing
+	}
+	events := o.events
+	o.mutex.Unlock()
+
+	// Wait for the event loop to handle events already emitted.
+	if opened {
+		barrier := make(chan struct{})
+
+		select {
+		case events <- message{barrier: barrier}:
+			<-barrier
+		case <-o.ctx.Done():
+		case <-ctx.Done():
+		}
+	}
+
+	// Send renamed files waiting for their new name, saved files waiting
+	// to be quiet, and written files waiting to be stable.
+	o.flushRenames()
+	o.flushSaves()
+	o.flushStable()
+
+	// Lock:
+	// 1. operations on listeners array (flushBuffer).
+	// 2. operations on buffers map.
+	// 3. operations on running listeners map.
+	o.mutex.Lock()
+
+	// Flush the event buffer, events received from now on are not buffered.
+	o.flushBuffer()
+	o.bufferFlushed = true
+
+	// Wait for running listeners.
+	drained := make(chan struct{})
+	if o.runningCount == 0 {
+		close(drained)
+	} else {
+		o.drained = drained
+	}
+	o.unlock()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = &ShutdownError{Listeners: o.runningListeners(), Err: ctx.Err()}
+	}
+
+	o.Close()
+
+	return err
+}
+
+// dispatch runs a listener with an event, and keeps track of the
+// running listeners.
+func (o *Observer) dispatch(l listener, event interface{}) {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using dispatch must be locked
+	// for operations using o.running and o.listeners.
+	o.consume(l)
+
+	if o.running == nil {
+		o.running = make(map[uint64]int)
+	}
+	o.running[l.id]++
+	o.runningCount++
+	o.dispatched++
+
+	ctx := o.ctx
+
+	// Queue the event, if the listener calls are sequential.
+	if o.deliveryMode == Sequential {
+		o.enqueue(ctx, l, event)
+		return
+	}
+
+	// Submit the event to the dispatch pool, after the mutex is released.
+	if o.pool != nil {
+		o.outbox = append(o.outbox, job{ctx: ctx, l: l, event: event})
+		return
+	}
+
+	go func() {
+		defer o.done(l)
+
+		o.run(ctx, l, event)
+	}()
+}
+
+// run runs a listener with an event, and report listener panics and errors,
+// errors of calls dispatched by EmitSync are sent to the caller.
+func (o *Observer) run(ctx context.Context, l listener, event interface{}) {
+	if c, ok := event.(*syncCall); ok {
+		err := call(c.ctx, l, c.event)
+		if err != nil {
+			err = &ListenerError{ID: l.id, Err: err}
+		}
+		c.results <- err
+
+		return
+	}
+
+	if err := call(ctx, l, event); err != nil {
+		o.reportError(&ListenerError{ID: l.id, Err: err})
+	}
+}
+
+// done marks a listener run as finished.
+func (o *Observer) done(l listener) {
+	// Lock:
+	// 1. operations on running listeners map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.running[l.id]--
+	if o.running[l.id] == 0 {
+		delete(o.running, l.id)
+	}
+	o.runningCount--
+
+	// Signal Shutdown that all listeners are done.
+	if o.runningCount == 0 && o.drained != nil {
+		close(o.drained)
+		o.drained = nil
+	}
+}
+
+// runningListeners returns the subscription ids of running listeners.
+func (o *Observer) runningListeners() []uint64 {
+	// Lock:
+	// 1. operations on running listeners map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	ids := make([]uint64, 0, len(o.running))
+	for id := range o.running {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids
+}
@@ -0,0 +1,92 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	var o Observer
+	var received int32
+
+	o.Open()
+
+	// Buffer events for a long time, Shutdown should flush them.
+	o.SetBufferDuration(time.Hour)
+
+	o.AddListener(func(e interface{}) {
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&received, int32(len(e.([]interface{}))))
+	})
+
+	o.Emit("hello")
+	o.Emit("world")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := o.Shutdown(ctx); err != nil {
+		t.Errorf("error shutting down Observer: %v.", err)
+	}
+
+	if atomic.LoadInt32(&received) != 2 {
+		t.Error("error flushing buffered events on Shutdown.")
+	}
+
+	// Emitting on a closed observer should not block.
+	if err := o.Emit("done"); err != ErrClosed {
+		t.Error("error Emitting on a closed Observer.")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	var o Observer
+
+	o.Open()
+
+	started := make(chan bool)
+	release := make(chan bool)
+	defer close(release)
+
+	o.AddListener(func(e interface{}) {})
+	sub := o.AddListener(func(e interface{}) {
+		started <- true
+		<-release
+	})
+
+	o.Emit("hello")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := o.Shutdown(ctx)
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error reporting listeners running after Shutdown: %v.", err)
+	}
+
+	if len(shutdownErr.Listeners) != 1 || shutdownErr.Listeners[0] != sub.ID() {
+		t.Errorf("error reporting running listener ids: %v.", shutdownErr.Listeners)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pendingStable is the file operations received for a file, waiting for
+// the file size and modification time to be stable.
+type pendingStable struct {
+	ops     Op
+	removed bool // A Remove or Rename event was received for the file.
+	size    int64
+	modTime time.Time
+	timer   Timer
+}
+
+// SetStableDuration set the file stable duration, when set Create and Write
+// events of a file are held until the file size and modification time did
+// not change during the quiet duration, or until a CloseWrite event is
+// received for the file, then one event is sent with the held operations.
+//
+// The fsnotify and polling backends do not send CloseWrite events, custom
+// backends may send CloseWrite when a file opened for writing is closed.
+// Zero quiet duration disable stable detection.
+func (o *Observer) SetStableDuration(quiet time.Duration) {
+	o.init()
+
+	// Lock:
+	// 1. operations on the stable map.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.stableQuiet = quiet
+}
+
+// holdUnstable holds Create and Write events until the file is stable, it
+// returns the event to send and a boolean asserting whether there is an
+// event to send now.
+func (o *Observer) holdUnstable(e WatchEvent) (WatchEvent, bool) {
+	// Get the file state before locking.
+	info, _ := os.Stat(e.Name)
+
+	// Lock:
+	// 1. operations on the stable map.
+	// 2. operations using the watchPatterns set (matchName).
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// Removed files are reported by the Remove event, and their held
+	// events are dropped.
+	if p, ok := o.stable[e.Name]; ok && e.Op&(Remove|Rename) != 0 {
+		p.removed = true
+	}
+
+	if o.stableQuiet == 0 || e.Op&(Create|Write|CloseWrite) == 0 {
+		return e, true
+	}
+
+	// Directories and files that are not watched are not held.
+	name := e.Name
+	if _, isDir := o.watchDirs[filepath.Clean(name)]; isDir || !o.matchName(name, AllOps) {
+		return e, true
+	}
+
+	if o.stable == nil {
+		o.stable = make(map[string]*pendingStable)
+	}
+	p, ok := o.stable[name]
+
+	// The writer closed the file, send the held events now.
+	if e.Op&CloseWrite == CloseWrite {
+		if !ok {
+			return e, true
+		}
+
+		p.timer.Stop()
+		delete(o.stable, name)
+
+		return WatchEvent{Name: name, Op: p.ops}, true
+	}
+
+	// Each event restarts the file quiet duration.
+	if ok {
+		p.timer.Stop()
+	} else {
+		p = &pendingStable{}
+		o.stable[name] = p
+	}
+
+	p.ops |= e.Op
+	if e.Op&Create == Create {
+		p.removed = false
+	}
+	if info != nil {
+		p.size = info.Size()
+		p.modTime = info.ModTime()
+	}
+	p.timer = o.clock.AfterFunc(o.stableQuiet, func() {
+		o.checkStable(name, p)
+	})
+
+	return e, false
+}
+
+// checkStable send the held events of a file if the file is stable, o/w
+// wait for another quiet duration.
+func (o *Observer) checkStable(name string, p *pendingStable) {
+	// Get the file state before locking.
+	info, err := os.Stat(name)
+
+	// Lock:
+	// 1. operations on the stable map.
+	o.mutex.Lock()
+
+	// The file was already sent, or has newer events.
+	if o.stable[name] != p {
+		o.mutex.Unlock()
+		return
+	}
+
+	// The file changed during the quiet duration.
+	if err == nil && (info.Size() != p.size || !info.ModTime().Equal(p.modTime)) {
+		p.size = info.Size()
+		p.modTime = info.ModTime()
+		p.timer = o.clock.AfterFunc(o.stableQuiet, func() {
+			o.checkStable(name, p)
+		})
+
+		o.mutex.Unlock()
+		return
+	}
+	delete(o.stable, name)
+	o.mutex.Unlock()
+
+	// Removed files are reported by the Remove event, files that can not be
+	// checked, e.g. files of test backends, are sent with the held events.
+	if p.removed {
+		return
+	}
+
+	o.handleWatchEvent(WatchEvent{Name: name, Op: p.ops})
+}
+
+// flushStable send all held events now.
+func (o *Observer) flushStable() {
+	// Lock:
+	// 1. operations on the stable map.
+	o.mutex.Lock()
+	stable := o.stable
+	o.stopStable()
+	o.mutex.Unlock()
+
+	for name, p := range stable {
+		if !p.removed {
+			o.handleWatchEvent(WatchEvent{Name: name, Op: p.ops})
+		}
+	}
+}
+
+// stopStable stop the held events timers, and drop held events.
+func (o *Observer) stopStable() {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using stopStable must be locked
+	// for operations using o.stable.
+	for _, p := range o.stable {
+		p.timer.Stop()
+	}
+
+	o.stable = nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetStableDuration(t *testing.T) {
+	var o Observer
+
+	events := make(chan WatchEvent, 10)
+
+	dir, err := ioutil.TempDir("", "tests")
+	if err != nil {
+		t.Error("error create temp dir.")
+	}
+	defer os.RemoveAll(dir) // clean up
+	tmpfn := filepath.Join(dir, "test_stable.bin")
+
+	// watch temporary file until it is stable
+	o.SetStableDuration(300 * time.Millisecond)
+	o.Watch([]string{tmpfn})
+	defer o.Close()
+
+	o.AddListener(func(e interface{}) {
+		events <- e.(WatchEvent)
+	})
+
+	// Write the file in chunks.
+	f, err := os.Create(tmpfn)
+	if err != nil {
+		t.Fatal("error creating temp file.")
+	}
+	for i := 0; i < 5; i++ {
+		time.Sleep(100 * time.Millisecond)
+		f.Write([]byte("chunk"))
+	}
+	written := time.Now()
+	f.Close()
+
+	select {
+	case e := <-events:
+		if e.Name != tmpfn || e.Op&Write != Write {
+			t.Errorf("error waiting for stable file, received %v.", e)
+		}
+		if time.Since(written) < 200*time.Millisecond {
+			t.Error("error waiting for stable file, event sent too early.")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("error waiting for stable file.")
+	}
+
+	// Only one event is sent.
+	select {
+	case e := <-events:
+		t.Errorf("error waiting for stable file, received another event %v.", e)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// State describes the observer life cycle state.
+type State int
+
+// These are the observer life cycle states.
+const (
+	StateNew     State = iota // Observer created, events can not be emitted.
+	StateOpen                 // Observer opened, events can be emitted.
+	StateClosing              // Observer shutting down, events can not be emitted.
+	StateClosed               // Observer closed.
+)
+
+// DefaultQueueSize is the default number of emitted events waiting for the
+// event loop.
+const DefaultQueueSize = 128
+
+// Errors returned when emitting events.
+var (
+	ErrNotOpen    = errors.New("Observer not opened.")
+	ErrClosed     = errors.New("Observer closed.")
+	ErrWouldBlock = errors.New("Observer busy.")
+)
+
+// String returns the state name.
+func (s State) String() string {
+	switch s {
+	case StateNew:
+		return "NEW"
+	case StateOpen:
+		return "OPEN"
+	case StateClosing:
+		return "CLOSING"
+	case StateClosed:
+		return "CLOSED"
+	}
+
+	return ""
+}
+
+// State returns the observer life cycle state.
+func (o *Observer) State() State {
+	o.init()
+
+	// Lock:
+	// 1. operations on observer state.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return o.state
+}
+
+// SetQueueSize set the number of emitted events waiting for the event loop,
+// when the queue is full Emit blocks and TryEmit returns ErrWouldBlock, the
+// default size is DefaultQueueSize. It will return an error if the observer
+// is already opened.
+func (o *Observer) SetQueueSize(n int) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on observer state.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.state != StateNew {
+		return fmt.Errorf("Observer already inititated.")
+	}
+
+	if n < 1 {
+		return fmt.Errorf("Invalid queue size.")
+	}
+
+	o.queueSize = n
+	return nil
+}
+
+// TryEmit emit an event without blocking, it will return ErrWouldBlock if
+// the event queue is full.
+func (o *Observer) TryEmit(event interface{}) error {
+	return o.emit(message{event: event}, false)
+}
+
+// TryEmitTopic emit an event on a topic without blocking, it will return
+// ErrWouldBlock if the event queue is full.
+func (o *Observer) TryEmitTopic(topic string, event interface{}) error {
+	return o.emit(message{topic: topic, event: event}, false)
+}
+
+// emit send a message to the event loop, if block is false and the event
+// queue is full, it will return ErrWouldBlock.
+func (o *Observer) emit(m message, block bool) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on observer state.
+	o.mutex.Lock()
+	state := o.state
+	o.mutex.Unlock()
+
+	switch state {
+	case StateNew:
+		return ErrNotOpen
+	case StateClosing, StateClosed:
+		return ErrClosed
+	}
+
+	if !block {
+		select {
+		case o.events <- m:
+			return nil
+		case <-o.ctx.Done():
+			return ErrClosed
+		default:
+			return ErrWouldBlock
+		}
+	}
+
+	select {
+	case o.events <- m:
+		return nil
+	case <-o.ctx.Done():
+		return ErrClosed
+	}
+}
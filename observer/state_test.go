@@ -0,0 +1,90 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestState(t *testing.T) {
+	var o Observer
+
+	if o.State() != StateNew {
+		t.Error("error getting state of a new Observer.")
+	}
+
+	if err := o.Emit("done"); err != ErrNotOpen {
+		t.Error("error Emitting on a not opened Observer.")
+	}
+
+	o.Open()
+	if o.State() != StateOpen {
+		t.Error("error getting state of an opened Observer.")
+	}
+
+	o.Close()
+	if o.State() != StateClosed {
+		t.Error("error getting state of a closed Observer.")
+	}
+
+	if err := o.Emit("done"); err != ErrClosed {
+		t.Error("error Emitting on a closed Observer.")
+	}
+
+	if err := o.Open(); err == nil {
+		t.Error("no error trying to reopen a closed Observer.")
+	}
+}
+
+func TestTryEmit(t *testing.T) {
+	var o Observer
+
+	if err := o.TryEmit("done"); err != ErrNotOpen {
+		t.Error("error trying to Emit on a not opened Observer.")
+	}
+
+	if err := o.SetQueueSize(100); err != nil {
+		t.Error("error setting the event queue size.")
+	}
+	o.Open()
+	defer o.Close()
+
+	if err := o.SetQueueSize(10); err == nil {
+		t.Error("no error setting the queue size of an opened Observer.")
+	}
+
+	done := make(chan bool, 100)
+	o.AddListener(func(e interface{}) {
+		done <- true
+	})
+
+	// A burst of events fits in the event queue.
+	for i := 0; i < 100; i++ {
+		if err := o.TryEmit(i); err != nil {
+			t.Fatalf("error trying to Emit event %d: %v.", i, err)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("error receiving queued events.")
+		}
+	}
+}
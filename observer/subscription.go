@@ -0,0 +1,115 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"fmt"
+)
+
+// Subscription is a handle of a registered listener.
+type Subscription struct {
+	id uint64
+	o  *Observer
+}
+
+// listenerFunc is the function type of registered listeners.
+type listenerFunc func(context.Context, interface{}) error
+
+// listener is a registered listener function.
+type listener struct {
+	id     uint64
+	topic  string
+	fn     listenerFunc
+	accept func(interface{}) bool // Events dispatched to the listener, nil accepts all events.
+}
+
+// accepts returns a boolean asserting whether an event is dispatched to
+// the listener.
+func (l listener) accepts(event interface{}) bool {
+	return l.accept == nil || l.accept(event)
+}
+
+// ID returns the subscription unique id.
+func (s Subscription) ID() uint64 {
+	return s.id
+}
+
+// Unsubscribe removes the subscription listener from the observer,
+// it will return an error if the listener was already removed.
+func (s Subscription) Unsubscribe() error {
+	if s.o == nil {
+		return fmt.Errorf("Subscription not registered.")
+	}
+
+	return s.o.RemoveListener(s)
+}
+
+// RemoveListener removes a listener added using AddListener,
+// AddContextListener, AddErrorListener or On,
+// it is safe to call RemoveListener from inside a running listener,
+// events already sent to the listener may still be running after it is removed.
+func (o *Observer) RemoveListener(s Subscription) error {
+	o.init()
+
+	// Lock:
+	// 1. operations on array listeners
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	// Subscription ids are unique only within one observer.
+	if s.o != o {
+		return fmt.Errorf("Subscription not registered.")
+	}
+
+	if !o.removeListener(s.id) {
+		return fmt.Errorf("Listener not found.")
+	}
+
+	return nil
+}
+
+// removeListener removes a listener, it returns a boolean asserting whether
+// the listener was found.
+func (o *Observer) removeListener(id uint64) bool {
+	// NOTE: we do not lock this function directly.
+	//
+	// All functions using removeListener must be locked
+	// for operations using o.listeners.
+	for i, l := range o.listeners {
+		if l.id != id {
+			continue
+		}
+
+		// Copy the listeners array, so the listeners array is never
+		// modified in place.
+		listeners := make([]listener, 0, len(o.listeners)-1)
+		listeners = append(listeners, o.listeners[:i]...)
+		o.listeners = append(listeners, o.listeners[i+1:]...)
+
+		// Stop the listener expiry timer.
+		delete(o.remaining, id)
+		if t, ok := o.expiry[id]; ok {
+			t.Stop()
+			delete(o.expiry, id)
+		}
+
+		return true
+	}
+
+	return false
+}
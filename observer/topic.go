@@ -0,0 +1,109 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"strings"
+)
+
+// Topic words are separated by dots, in subscription topics "*" matches
+// exactly one word and "#" matches zero or more words.
+//
+// For example "config.*" matches "config.db" but not "config.db.user",
+// and "fs.#" matches "fs", "fs.write" and "fs.write.tmp".
+const (
+	// AllTopics is the subscription topic matching all events.
+	AllTopics = "#"
+
+	// WatchTopic is the topic prefix of file watch events, file watch events
+	// are emitted on "fs.create", "fs.write", "fs.remove", "fs.rename",
+	// "fs.chmod", "fs.modified" and "fs.close_write", and file watcher
+	// errors on "fs.error".
+	WatchTopic = "fs"
+)
+
+// message is an event emitted on a topic.
+type message struct {
+	topic   string
+	event   interface{}
+	barrier chan struct{}
+}
+
+// On adds a listener function to run on events emitted on matching topics,
+// the returned subscription can be used to remove the listener.
+func (o *Observer) On(topic string, l Listener) Subscription {
+	return o.addListener(topic, l)
+}
+
+// EmitTopic emit an event on a topic, listeners subscribed to a matching
+// topic will be called using the event object, it will return ErrNotOpen if
+// the observer is not opened and ErrClosed if the observer is closed.
+func (o *Observer) EmitTopic(topic string, event interface{}) error {
+	return o.emit(message{topic: topic, event: event}, true)
+}
+
+// watchTopic returns the topic of a file watch event.
+func watchTopic(op Op) string {
+	// Use the first file operation in the event.
+	for _, o := range []Op{Create, Write, Remove, Rename, Chmod, Modified, CloseWrite} {
+		if op&o == o {
+			return WatchTopic + "." + strings.ToLower(o.String())
+		}
+	}
+
+	return WatchTopic
+}
+
+// topicWords splits a topic into words, the empty topic has no words.
+func topicWords(topic string) []string {
+	if topic == "" {
+		return nil
+	}
+
+	return strings.Split(topic, ".")
+}
+
+// matchTopic returns a boolean asserting whether a topic matches a
+// subscription topic.
+func matchTopic(pattern string, topic string) bool {
+	return matchWords(topicWords(pattern), topicWords(topic))
+}
+
+// matchWords matches topic words against subscription topic words.
+func matchWords(pattern []string, topic []string) bool {
+	for len(pattern) > 0 {
+		// Try to match the rest of the pattern after every word.
+		if pattern[0] == "#" {
+			for i := 0; i <= len(topic); i++ {
+				if matchWords(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(topic) == 0 || (pattern[0] != "*" && pattern[0] != topic[0]) {
+			return false
+		}
+
+		pattern = pattern[1:]
+		topic = topic[1:]
+	}
+
+	return len(topic) == 0
+}
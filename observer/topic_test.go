@@ -0,0 +1,87 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	matches := []struct {
+		pattern string
+		topic   string
+		match   bool
+	}{
+		{"config", "config", true},
+		{"config", "config.db", false},
+		{"config.*", "config.db", true},
+		{"config.*", "config", false},
+		{"config.*", "config.db.user", false},
+		{"*.db", "config.db", true},
+		{"fs.#", "fs", true},
+		{"fs.#", "fs.write", true},
+		{"fs.#", "fs.write.tmp", true},
+		{"fs.#", "config.db", false},
+		{"#", "", true},
+		{"#", "config.db", true},
+		{"#.db", "config.db", true},
+		{"", "", true},
+		{"", "config", false},
+	}
+
+	for _, m := range matches {
+		if matchTopic(m.pattern, m.topic) != m.match {
+			t.Errorf("error matching topic %s with %s.", m.topic, m.pattern)
+		}
+	}
+}
+
+func TestOn(t *testing.T) {
+	var o Observer
+
+	o.Open()
+	defer o.Close()
+
+	done := make(chan string, 10)
+
+	o.On("config.*", func(e interface{}) {
+		done <- "config " + e.(string)
+	})
+	o.On("fs.#", func(e interface{}) {
+		done <- "fs " + e.(string)
+	})
+
+	o.EmitTopic("fs.write", "write")
+	o.EmitTopic("config.db", "db")
+	o.EmitTopic("config.db.user", "user")
+	o.Emit("untopic")
+
+	received := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		received[<-done] = true
+	}
+
+	if !received["config db"] || !received["fs write"] {
+		t.Error("error receiving topic events.")
+	}
+
+	select {
+	case e := <-done:
+		t.Errorf("error receiving unsubscribed topic event %s.", e)
+	default:
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"time"
+)
+
+// TypedListener is the function type to run on events of type T.
+type TypedListener[T any] func(T)
+
+// TypedBatchListener is the function type to run on buffered events of type T.
+type TypedBatchListener[T any] func([]T)
+
+// TypedObserver emplements the observer pattern for events of type T,
+// listeners will recive only events of type T.
+//
+// The untyped observer methods are available using the embedded Observer,
+// for example, watching files using a TypedObserver[WatchEvent]:
+//
+//	o := observer.TypedObserver[observer.WatchEvent]{}
+//	o.Watch([]string{"*.conf"})
+//	o.AddListener(func(e observer.WatchEvent) {
+//		log.Printf("File modified: %s.\n", e.Name)
+//	})
+type TypedObserver[T any] struct {
+	Observer
+}
+
+// AddListener adds a listener function to run on events of type T,
+// when events are buffered the listener will be called once for each
+// event in the buffer.
+func (o *TypedObserver[T]) AddListener(l TypedListener[T]) Subscription {
+	return o.Observer.AddListener(typedListener(l))
+}
+
+// AddBatchListener adds a listener function to run on events of type T,
+// when events are buffered the listener will be called once with all the
+// events of type T in the buffer, o/w it will be called with one event.
+func (o *TypedObserver[T]) AddBatchListener(l TypedBatchListener[T]) Subscription {
+	return o.Observer.AddListener(typedBatchListener(l))
+}
+
+// On adds a listener function to run on events of type T emitted on
+// matching topics.
+func (o *TypedObserver[T]) On(topic string, l TypedListener[T]) Subscription {
+	return o.Observer.On(topic, typedListener(l))
+}
+
+// Emit an event of type T.
+func (o *TypedObserver[T]) Emit(event T) error {
+	return o.Observer.Emit(event)
+}
+
+// EmitTopic emit an event of type T on a topic.
+func (o *TypedObserver[T]) EmitTopic(topic string, event T) error {
+	return o.Observer.EmitTopic(topic, event)
+}
+
+// TryEmit try to emit an event of type T without blocking, same as
+// Observer.TryEmit.
+func (o *TypedObserver[T]) TryEmit(event T) error {
+	return o.Observer.TryEmit(event)
+}
+
+// TryEmitTopic try to emit an event of type T on a topic without blocking,
+// same as Observer.TryEmitTopic.
+func (o *TypedObserver[T]) TryEmitTopic(topic string, event T) error {
+	return o.Observer.TryEmitTopic(topic, event)
+}
+
+// EmitSync emit an event of type T and wait for the listeners to finish,
+// same as Observer.EmitSync.
+func (o *TypedObserver[T]) EmitSync(ctx context.Context, event T) error {
+	return o.Observer.EmitSync(ctx, event)
+}
+
+// EmitTopicSync emit an event of type T on a topic and wait for the
+// listeners to finish, same as Observer.EmitTopicSync.
+func (o *TypedObserver[T]) EmitTopicSync(ctx context.Context, topic string, event T) error {
+	return o.Observer.EmitTopicSync(ctx, topic, event)
+}
+
+// Once adds a listener function to run on the first event of type T, the
+// listener is removed after it is called.
+func (o *TypedObserver[T]) Once(l TypedListener[T]) Subscription {
+	return o.AddListenerN(1, l)
+}
+
+// AddListenerN adds a listener function to run on the next n events of
+// type T, the listener is removed after it is called n times, events of
+// other types are not counted.
+func (o *TypedObserver[T]) AddListenerN(n int, l TypedListener[T]) Subscription {
+	return o.Observer.addListenerN(n, limitedListener(typedListener(l), acceptTyped[T]))
+}
+
+// AddListenerTTL adds a listener function to run on events of type T
+// during ttl, the listener is removed when ttl expires.
+func (o *TypedObserver[T]) AddListenerTTL(ttl time.Duration, l TypedListener[T]) Subscription {
+	return o.Observer.addListenerTTL(ttl, limitedListener(typedListener(l), acceptTyped[T]))
+}
+
+// WaitFor blocks until an event of type T matching predicate is emitted,
+// and returns the event, a nil predicate matches any event of type T,
+// same as Observer.WaitFor.
+//
+// In buffered mode predicate receives each buffered event of type T.
+func (o *TypedObserver[T]) WaitFor(ctx context.Context, predicate func(T) bool) (event T, err error) {
+	o.init()
+
+	events := make(chan T, 1)
+	s := o.AddListener(func(e T) {
+		if predicate != nil && !predicate(e) {
+			return
+		}
+
+		// Keep only the first matching event.
+		select {
+		case events <- e:
+		default:
+		}
+	})
+	defer s.Unsubscribe()
+
+	select {
+	case event = <-events:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-o.ctx.Done():
+		err = ErrClosed
+	}
+
+	return
+}
+
+// acceptTyped returns a boolean asserting whether an event, or buffered
+// events, hold events of type T.
+func acceptTyped[T any](e interface{}) bool {
+	return len(typedEvents[T](e)) > 0
+}
+
+// typedEvents returns the events of type T in an event or buffered events.
+//
+// Buffered events are checked first, because when T is an interface type,
+// e.g. any, the buffered events array is also of type T.
+func typedEvents[T any](e interface{}) []T {
+	if v, ok := e.([]interface{}); ok {
+		events := make([]T, 0, len(v))
+		for _, event := range v {
+			if t, ok := event.(T); ok {
+				events = append(events, t)
+			}
+		}
+
+		return events
+	}
+
+	if v, ok := e.(T); ok {
+		return []T{v}
+	}
+
+	return nil
+}
+
+// typedListener wraps a typed listener, events of other types are ignored.
+func typedListener[T any](l TypedListener[T]) Listener {
+	return func(e interface{}) {
+		for _, event := range typedEvents[T](e) {
+			l(event)
+		}
+	}
+}
+
+// typedBatchListener wraps a typed batch listener, events of other types
+// are ignored.
+func typedBatchListener[T any](l TypedBatchListener[T]) Listener {
+	return func(e interface{}) {
+		if events := typedEvents[T](e); len(events) > 0 {
+			l(events)
+		}
+	}
+}
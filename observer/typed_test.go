@@ -0,0 +1,184 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTypedAddListener(t *testing.T) {
+	var o TypedObserver[string]
+
+	o.Open()
+	defer o.Close()
+
+	done := make(chan string)
+	defer close(done)
+
+	o.AddListener(func(e string) {
+		done <- e
+	})
+
+	// Events of other types are ignored.
+	o.Observer.Emit(42)
+	o.Emit("done")
+
+	if output := <-done; output != "done" {
+		t.Error("error Emitting typed events.")
+	}
+}
+
+func TestTypedAddBatchListener(t *testing.T) {
+	var o TypedObserver[string]
+
+	o.Open()
+	defer o.Close()
+
+	done := make(chan []string)
+	defer close(done)
+
+	o.SetBufferDuration(100 * time.Millisecond)
+
+	o.AddBatchListener(func(e []string) {
+		done <- e
+	})
+
+	o.Emit("hello")
+	o.Observer.Emit(42)
+	o.Emit("world")
+
+	output := <-done
+	if len(output) != 2 || output[0] != "hello" || output[1] != "world" {
+		t.Error("error sending typed buffered events.")
+	}
+}
+
+func TestTypedAnyBatchListener(t *testing.T) {
+	var o TypedObserver[any]
+
+	o.Open()
+	defer o.Close()
+
+	done := make(chan []any)
+	defer close(done)
+
+	o.SetBufferDuration(100 * time.Millisecond)
+
+	o.AddBatchListener(func(e []any) {
+		done <- e
+	})
+
+	o.Emit("hello")
+	o.Emit(42)
+
+	output := <-done
+	if len(output) != 2 || output[0] != "hello" || output[1] != 42 {
+		t.Errorf("error sending buffered events of type any, received %v.", output)
+	}
+}
+
+func TestTypedOnce(t *testing.T) {
+	var o TypedObserver[string]
+
+	o.Open()
+	defer o.Close()
+
+	done := make(chan string, 10)
+
+	o.Once(func(e string) {
+		done <- e
+	})
+
+	// Events of other types are not counted.
+	o.Observer.Emit(42)
+	o.Emit("hello")
+	o.Emit("world")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	o.Shutdown(ctx)
+
+	if len(done) != 1 || <-done != "hello" {
+		t.Error("error calling a typed once listener.")
+	}
+}
+
+func TestTypedWaitFor(t *testing.T) {
+	var o TypedObserver[int]
+
+	o.Open()
+	defer o.Close()
+
+	// Emit events after WaitFor is waiting.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		o.Observer.Emit("hello")
+		o.Emit(1)
+		o.Emit(2)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e, err := o.WaitFor(ctx, func(e int) bool {
+		return e > 1
+	})
+	if err != nil || e != 2 {
+		t.Errorf("error waiting for a typed event, received %v %v.", e, err)
+	}
+}
+
+func TestTypedTryEmit(t *testing.T) {
+	var o TypedObserver[string]
+
+	o.Open()
+	defer o.Close()
+
+	done := make(chan string)
+	defer close(done)
+
+	o.AddListener(func(e string) {
+		done <- e
+	})
+
+	if err := o.TryEmit("done"); err != nil {
+		t.Errorf("error trying to Emit a typed event: %v.", err)
+	}
+
+	if output := <-done; output != "done" {
+		t.Error("error trying to Emit typed events.")
+	}
+}
+
+func TestTypedEmitSync(t *testing.T) {
+	var o TypedObserver[string]
+
+	o.Open()
+	defer o.Close()
+
+	var output string
+	o.AddListener(func(e string) {
+		output = e
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := o.EmitSync(ctx, "done"); err != nil || output != "done" {
+		t.Errorf("error emitting a typed synchronous event: %v.", err)
+	}
+}
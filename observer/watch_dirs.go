@@ -0,0 +1,248 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// watchPattern returns the watch pattern for a user file name.
+func watchPattern(f string) string {
+	// For example if file is '/home/.config/*.conf':
+	// base will be '*.conf'
+	// dir will be '/home/.config'
+	base := filepath.Base(f)
+	dir := filepath.Dir(f)
+
+	// Pattern calculation does not allways equal f from user.
+	// We can not use the user provided file name here, because
+	// in cases where we have no directory with the file name, we
+	// do want to add the current directory './' before the base file
+	// name. We can not use filepath.Join for the same reason, it will
+	// remove the './' prefix when cleaning filename.
+	return fmt.Sprintf("%s%s%s", dir, string(filepath.Separator), base)
+}
+
+// patternDirs returns the directories watched for a pattern, recursive
+// patterns walk the file system.
+func (o *Observer) patternDirs(pattern string) ([]string, error) {
+	// Non recursive patterns watch only one directory.
+	if !isRecursive(pattern) {
+		return []string{filepath.Dir(pattern)}, nil
+	}
+
+	return o.walkDirs(recursiveRoot(pattern))
+}
+
+// walkDirs returns root and all the directories under it, ignored
+// directories are skipped.
+func (o *Observer) walkDirs(root string) (dirs []string, err error) {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Directories removed while walking are not an error.
+			if os.IsNotExist(err) && path != root {
+				return nil
+			}
+
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		if path != root && o.ignored(path, true) {
+			return filepath.SkipDir
+		}
+
+		o.loadIgnoreFiles(path)
+		dirs = append(dirs, filepath.Clean(path))
+
+		return nil
+	})
+
+	return
+}
+
+// recursivePatterns returns the watched recursive patterns using a
+// directory.
+func (o *Observer) recursivePatterns(dir string) (patterns []string) {
+	for _, p := range o.watchPatterns.Values() {
+		if isRecursive(p) && isUnder(recursiveRoot(p), dir) {
+			patterns = append(patterns, p)
+		}
+	}
+
+	return
+}
+
+// releaseRef remove a directory from the directories referenced by
+// watch patterns.
+func (o *Observer) releaseRef(dir string) {
+	for p, dirs := range o.patternRefs {
+		for i, d := range dirs {
+			if d == dir {
+				o.patternRefs[p] = append(dirs[:i:i], dirs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// releaseDir release one reference of a watched directory, when no pattern
+// is using the directory it is removed from the file watcher.
+func (o *Observer) releaseDir(dir string) {
+	if _, ok := o.watchDirs[dir]; !ok {
+		return
+	}
+
+	o.watchDirs[dir]--
+	if o.watchDirs[dir] > 0 {
+		return
+	}
+
+	delete(o.watchDirs, dir)
+
+	// Logging removed directories.
+	if o.Verbose {
+		log.Printf("[Debug] Removing dir: %s", dir)
+	}
+
+	// The directory may already be removed from the file system,
+	// so we ignore the returned error.
+	o.backend.Remove(dir)
+}
+
+// handleDirEvent keeps the watched directories in sync with directories
+// created or removed on disk, it returns Create events for files found
+// inside new directories, those files may be created before the new
+// directory is watched.
+func (o *Observer) handleDirEvent(e WatchEvent) (events []WatchEvent) {
+	// Lock:
+	// 1. operations on watchDirs map.
+	// 2. operations on the file watcher.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	dir := filepath.Clean(e.Name)
+
+	// Reload modified ignore files.
+	if isIgnoreFile(dir) {
+		if _, ok := o.watchDirs[filepath.Dir(dir)]; ok {
+			o.loadIgnoreFiles(filepath.Dir(dir))
+		}
+	}
+
+	switch {
+	case e.Op&Create == Create:
+		events = o.addRecursiveDir(dir)
+	case e.Op&(Remove|Rename) != 0:
+		o.removeDir(dir)
+	}
+
+	return
+}
+
+// addRecursiveDir watch a new directory and it's sub directories if it is
+// under a recursive pattern root.
+func (o *Observer) addRecursiveDir(dir string) (events []WatchEvent) {
+	// Check that the new directory is used by a recursive pattern.
+	if _, ok := o.watchDirs[dir]; ok || len(o.recursivePatterns(dir)) == 0 {
+		return
+	}
+
+	// Check that the new file is a directory.
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if !info.IsDir() {
+			events = append(events, WatchEvent{Name: path, Op: Create})
+			return nil
+		}
+
+		if o.ignored(path, true) {
+			return filepath.SkipDir
+		}
+
+		if err := o.backend.Add(path); err != nil {
+			return nil
+		}
+		o.loadIgnoreFiles(path)
+
+		// Each recursive pattern holds one reference on the new directory.
+		for _, p := range o.recursivePatterns(path) {
+			if !hasString(o.patternRefs[p], path) {
+				o.patternRefs[p] = append(o.patternRefs[p], path)
+				o.watchDirs[path]++
+			}
+		}
+
+		// Logging watched directories.
+		if o.Verbose {
+			log.Printf("[Debug] Watching dir: %s", path)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// removeDir stop watching a removed directory and it's sub directories.
+func (o *Observer) removeDir(dir string) {
+	if _, ok := o.watchDirs[dir]; !ok {
+		return
+	}
+
+	for d := range o.watchDirs {
+		if !isUnder(dir, d) {
+			continue
+		}
+
+		// The watch may already be removed by the file watcher,
+		// so we ignore the returned error.
+		o.backend.Remove(d)
+		delete(o.watchDirs, d)
+		o.releaseRef(d)
+
+		// Logging removed directories.
+		if o.Verbose {
+			log.Printf("[Debug] Removing dir: %s", d)
+		}
+	}
+}
+
+// hasString returns a boolean asserting whether a string is in an array.
+func hasString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
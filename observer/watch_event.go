@@ -18,12 +18,20 @@ package observer
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 )
 
 // WatchEvent (fsnotify.Event) represents a single file system notification.
 type WatchEvent struct {
-	Name string // Relative path to the file or directory.
-	Op   Op     // File operation that triggered the event.
+	Name    string    // Relative path to the file or directory.
+	Op      Op        // File operation that triggered the event.
+	OldName string    // Old path of a renamed file, when rename tracking is set.
+	Cookie  uint32    // Id of related rename events, zero if unknown.
+	OldHash string    // Content hash before the event, when content hash is set.
+	Hash    string    // Content hash after the event, when content hash is set.
+	Pattern string    // Watch pattern matching the file, the exact file name or the first added pattern.
+	Info    *FileInfo // File metadata, when metadata is set.
 }
 
 // Op (fsnotify.Op) describes a set of file operations.
@@ -36,8 +44,50 @@ const (
 	Remove
 	Rename
 	Chmod
+
+	// Modified is a logical file change, sent when save coalescing is set.
+	Modified
+
+	// CloseWrite is a file opened for writing closed, sent by backends
+	// supporting it, e.g. inotify IN_CLOSE_WRITE.
+	CloseWrite
 )
 
+// DefaultOps are the file operations watched by Watch.
+const DefaultOps = Create | Write | Remove | Modified
+
+// AllOps are all the file operations.
+const AllOps = Create | Write | Remove | Rename | Chmod | Modified | CloseWrite
+
+// ParseOp parse a comma separated list of file operation names, e.g.
+// "create,write,rename", it will return an error for unknown names.
+func ParseOp(s string) (op Op, err error) {
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "create":
+			op |= Create
+		case "write":
+			op |= Write
+		case "remove":
+			op |= Remove
+		case "rename":
+			op |= Rename
+		case "chmod":
+			op |= Chmod
+		case "modified":
+			op |= Modified
+		case "closewrite":
+			op |= CloseWrite
+		case "all":
+			op |= AllOps
+		default:
+			return 0, fmt.Errorf("Unknown file operation %q.", name)
+		}
+	}
+
+	return
+}
+
 // String (fsnotify.Op.String)
 func (op Op) String() string {
 	// Use a buffer for efficient string concatenation
@@ -58,6 +108,12 @@ func (op Op) String() string {
 	if op&Chmod == Chmod {
 		buffer.WriteString("|CHMOD")
 	}
+	if op&Modified == Modified {
+		buffer.WriteString("|MODIFIED")
+	}
+	if op&CloseWrite == CloseWrite {
+		buffer.WriteString("|CLOSE_WRITE")
+	}
 	if buffer.Len() == 0 {
 		return ""
 	}
@@ -0,0 +1,38 @@
+// Copyright 2018 Yaacov Zamir <kobi.zamir@gmail.com>
+// and other contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package observer implements an event emitter and listener with builtin file watcher.
+package observer
+
+import (
+	"testing"
+)
+
+func TestParseOp(t *testing.T) {
+	op, err := ParseOp("create, Write,rename")
+	if err != nil || op != Create|Write|Rename {
+		t.Error("error parsing file operations.")
+	}
+
+	op, err = ParseOp("all")
+	if err != nil || op != AllOps {
+		t.Error("error parsing all file operations.")
+	}
+
+	_, err = ParseOp("create,open")
+	if err == nil {
+		t.Error("no error parsing unknown file operation.")
+	}
+}